@@ -1,11 +1,16 @@
 package uploadersdk
 
 import (
+	"context"
 	"fmt"
+	"io"
 	"path"
 	"path/filepath"
+	"time"
 
 	downloader "bytetrade.io/web3os/uploader-sdk/pkg/download"
+	"bytetrade.io/web3os/uploader-sdk/pkg/restic"
+	"bytetrade.io/web3os/uploader-sdk/pkg/storage"
 	uploader "bytetrade.io/web3os/uploader-sdk/pkg/upload"
 	"bytetrade.io/web3os/uploader-sdk/pkg/util"
 	"bytetrade.io/web3os/uploader-sdk/pkg/util/logger"
@@ -20,6 +25,63 @@ func init() {
 	}
 }
 
+type UploadResult = uploader.Result
+
+type ProgressEvent = restic.ProgressEvent
+
+type ProgressFunc = restic.ProgressFunc
+
+type Backend = storage.Backend
+
+type LocalBackend = storage.LocalBackend
+
+type S3Backend = storage.S3Backend
+
+type RestBackend = storage.RestBackend
+
+type RetentionPolicy = restic.RetentionPolicy
+
+type ForgetReport = restic.ForgetReport
+
+type ErrorPolicy = restic.ErrorPolicy
+
+type BackupOptions = restic.BackupOptions
+
+type RestoreOptions = restic.RestoreOptions
+
+type OverwritePolicy = restic.OverwritePolicy
+
+const (
+	OverwriteAlways    = restic.OverwriteAlways
+	OverwriteIfChanged = restic.OverwriteIfChanged
+	OverwriteIfNewer   = restic.OverwriteIfNewer
+	OverwriteNever     = restic.OverwriteNever
+)
+
+type FileError = restic.ErrorUpdate
+
+const (
+	ErrorPolicyWarn   = restic.ErrorPolicyWarn
+	ErrorPolicyFail   = restic.ErrorPolicyFail
+	ErrorPolicyIgnore = restic.ErrorPolicyIgnore
+)
+
+type DownloadResult = downloader.Result
+
+// PasswordProvider supplies the repository password in place of the
+// Password option, e.g. from a file restic reads itself.
+type PasswordProvider = storage.PasswordProvider
+
+type StaticPassword = storage.StaticPassword
+
+type PasswordFile = storage.PasswordFile
+
+type PasswordCommand = storage.PasswordCommand
+
+type KubeSecretPassword = storage.KubeSecretPassword
+
+type PasswordFunc = storage.PasswordFunc
+
 type UploadClient struct {
 	option uploader.Option
 }
@@ -28,12 +90,20 @@ type UploadClientOption struct {
 	Name                 string
 	UserName             string
 	Password             string
+	PasswordProvider     PasswordProvider
 	CloudName            string
 	CloudRegion          string
 	UploadPath           string
 	CloudApiMirror       string
+	CloudEndpoint        string
 	LimitUploadRate      string
 	StorageTokenDuration string
+	Timeout              time.Duration
+	Progress             ProgressFunc
+	Backend              Backend
+	Retention            *RetentionPolicy
+	ErrorPolicy          ErrorPolicy
+	BackupOptions        *BackupOptions
 	BaseDir              string
 	Version              string
 	Logger               *zap.SugaredLogger
@@ -46,12 +116,20 @@ func NewUploadClient(opt *UploadClientOption,
 		Name:                 opt.Name,
 		UserName:             opt.UserName,
 		Password:             opt.Password,
+		PasswordProvider:     opt.PasswordProvider,
 		CloudName:            opt.CloudName,
 		CloudRegion:          opt.CloudRegion,
 		UploadPath:           opt.UploadPath,
 		CloudApiMirror:       opt.CloudApiMirror,
+		CloudEndpoint:        opt.CloudEndpoint,
 		LimitUploadRate:      opt.LimitUploadRate,
 		StorageTokenDuration: opt.StorageTokenDuration,
+		Timeout:              opt.Timeout,
+		Progress:             opt.Progress,
+		Backend:              opt.Backend,
+		Retention:            opt.Retention,
+		ErrorPolicy:          opt.ErrorPolicy,
+		BackupOptions:        opt.BackupOptions,
 	}
 
 	var client = &UploadClient{
@@ -63,25 +141,46 @@ func NewUploadClient(opt *UploadClientOption,
 	return client
 }
 
-func (c *UploadClient) Upload() error {
+func (c *UploadClient) Upload() (*UploadResult, error) {
+	return c.UploadWithContext(context.Background())
+}
+
+// UploadWithContext runs the backup until it finishes, ctx is done or the
+// configured Timeout elapses; cancelling ctx stops the running restic process.
+// Under ErrorPolicyFail a snapshot that misses files is returned along with
+// ErrIncompleteSnapshot, and the Retention policy is not applied.
+func (c *UploadClient) UploadWithContext(ctx context.Context) (*UploadResult, error) {
 	u := &uploader.Upload{}
-	return u.Upload(c.option)
+	return u.Upload(ctx, c.option)
 }
 
-func (c *UploadClient) setLogger(baseDir string, version string, log *zap.SugaredLogger) {
-	if log != nil {
-		logger.SetLogger(log)
-		return
-	}
+// UploadStream backs up the content of rd as the file filename of a new
+// snapshot, e.g. a database dump that should never be written to disk. rd is
+// read once, so the backup fails instead of starting over when the storage
+// token expires after rd was partly read.
+func (c *UploadClient) UploadStream(ctx context.Context, filename string, rd io.Reader) (*UploadResult, error) {
+	var opt = c.option
+	opt.Stdin = rd
+	opt.StdinFilename = filename
 
-	installerPath := filepath.Join(baseDir, "versions", fmt.Sprintf("v%s", version))
-	if err := util.CreateDir(installerPath); err != nil {
-		panic(err)
-	}
+	u := &uploader.Upload{}
+	return u.Upload(ctx, opt)
+}
 
-	jsonLogDir := path.Join(baseDir, "logs")
-	consoleLogDir := path.Join(installerPath, "logs", "backup_upload.log")
-	logger.InitLog(jsonLogDir, consoleLogDir, true)
+// UploadCommand backs up the output of command as the file filename of a new
+// snapshot, restic starts the command and the backup fails when it exits
+// with a non-zero code. It requires restic 0.17 or later.
+func (c *UploadClient) UploadCommand(ctx context.Context, filename string, command ...string) (*UploadResult, error) {
+	var opt = c.option
+	opt.StdinFilename = filename
+	opt.StdinCommand = command
+
+	u := &uploader.Upload{}
+	return u.Upload(ctx, opt)
+}
+
+func (c *UploadClient) setLogger(baseDir string, version string, log *zap.SugaredLogger) {
+	setLogger(baseDir, version, "backup_upload.log", log)
 }
 
 //  download
@@ -95,12 +194,18 @@ type DownloadClientOption struct {
 	SnapshotId           string
 	UserName             string
 	Password             string
+	PasswordProvider     PasswordProvider
 	CloudName            string
 	CloudRegion          string
 	DownloadPath         string
 	CloudApiMirror       string
+	CloudEndpoint        string
 	LimitDownloadRate    string
 	StorageTokenDuration string
+	Timeout              time.Duration
+	Progress             ProgressFunc
+	Backend              Backend
+	RestoreOptions       *RestoreOptions
 	BaseDir              string
 	Version              string
 	Logger               *zap.SugaredLogger
@@ -108,15 +213,22 @@ type DownloadClientOption struct {
 
 func NewDownloadClient(opt *DownloadClientOption) *DownloadClient {
 	var o = downloader.Option{
-		Name:              opt.Name,
-		SnapshotId:        opt.SnapshotId,
-		UserName:          opt.UserName,
-		Password:          opt.Password,
-		CloudName:         opt.CloudName,
-		CloudRegion:       opt.CloudRegion,
-		DownloadPath:      opt.DownloadPath,
-		CloudApiMirror:    opt.CloudApiMirror,
-		LimitDownloadRate: opt.LimitDownloadRate,
+		Name:                 opt.Name,
+		SnapshotId:           opt.SnapshotId,
+		UserName:             opt.UserName,
+		Password:             opt.Password,
+		PasswordProvider:     opt.PasswordProvider,
+		CloudName:            opt.CloudName,
+		CloudRegion:          opt.CloudRegion,
+		DownloadPath:         opt.DownloadPath,
+		CloudApiMirror:       opt.CloudApiMirror,
+		CloudEndpoint:        opt.CloudEndpoint,
+		LimitDownloadRate:    opt.LimitDownloadRate,
+		StorageTokenDuration: opt.StorageTokenDuration,
+		Timeout:              opt.Timeout,
+		Progress:             opt.Progress,
+		Backend:              opt.Backend,
+		RestoreOptions:       opt.RestoreOptions,
 	}
 
 	var client = &DownloadClient{
@@ -128,17 +240,27 @@ func NewDownloadClient(opt *DownloadClientOption) *DownloadClient {
 	return client
 }
 
-func (c *DownloadClient) Download() error {
+func (c *DownloadClient) Download() (*DownloadResult, error) {
+	return c.DownloadWithContext(context.Background())
+}
+
+// DownloadWithContext runs the restore until it finishes, ctx is done or the
+// configured Timeout elapses; cancelling ctx stops the running restic process.
+func (c *DownloadClient) DownloadWithContext(ctx context.Context) (*DownloadResult, error) {
 	if !util.IsExist(c.option.DownloadPath) {
-		return errors.WithStack(fmt.Errorf("download path not found"))
+		return nil, errors.WithStack(fmt.Errorf("download path not found"))
 	}
 
 	d := &downloader.Download{}
 
-	return d.Download(c.option)
+	return d.Download(ctx, c.option)
 }
 
 func (c *DownloadClient) setLogger(baseDir string, version string, log *zap.SugaredLogger) {
+	setLogger(baseDir, version, "backup_download.log", log)
+}
+
+func setLogger(baseDir string, version string, logFile string, log *zap.SugaredLogger) {
 	if log != nil {
 		logger.SetLogger(log)
 		return
@@ -150,6 +272,6 @@ func (c *DownloadClient) setLogger(baseDir string, version string, log *zap.Suga
 	}
 
 	jsonLogDir := path.Join(baseDir, "logs")
-	consoleLogDir := path.Join(installerPath, "logs", "backup_download.log")
+	consoleLogDir := path.Join(installerPath, "logs", logFile)
 	logger.InitLog(jsonLogDir, consoleLogDir, true)
 }
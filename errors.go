@@ -0,0 +1,24 @@
+package uploadersdk
+
+import (
+	"bytetrade.io/web3os/uploader-sdk/pkg/restic"
+	"bytetrade.io/web3os/uploader-sdk/pkg/storage"
+)
+
+// Failure classes returned by the clients, match them with errors.Is.
+var (
+	ErrTokenExpired       = restic.ErrTokenExpired
+	ErrRepoLocked         = restic.ErrRepoLocked
+	ErrRepoNotFound       = restic.ErrRepoNotFound
+	ErrSnapshotNotFound   = restic.ErrSnapshotNotFound
+	ErrWrongPassword      = restic.ErrWrongPassword
+	ErrNetwork            = restic.ErrNetwork
+	ErrQuotaExceeded      = restic.ErrQuotaExceeded
+	ErrIncompleteSnapshot = restic.ErrIncompleteSnapshot
+	ErrSpaceNotEnabled    = storage.ErrSpaceNotEnabled
+	ErrCopyConflict       = storage.ErrCopyConflict
+)
+
+// ResticError carries the message restic printed for a failure, use
+// errors.As to retrieve it.
+type ResticError = restic.Error
@@ -2,6 +2,7 @@ package download
 
 import (
 	"context"
+	"time"
 
 	"bytetrade.io/web3os/uploader-sdk/pkg/restic"
 	"bytetrade.io/web3os/uploader-sdk/pkg/storage"
@@ -18,61 +19,109 @@ type Download struct {
 	option Option
 }
 
+// Result describes the files restored by a successful download.
+type Result struct {
+	SnapshotId    string        `json:"snapshot_id"`
+	TotalFiles    uint64        `json:"total_files"`
+	FilesRestored uint64        `json:"files_restored"`
+	FilesSkipped  uint64        `json:"files_skipped"`
+	TotalBytes    uint64        `json:"total_bytes"`
+	BytesRestored uint64        `json:"bytes_restored"`
+	BytesSkipped  uint64        `json:"bytes_skipped"`
+	Duration      time.Duration `json:"duration"`
+	RepoUrl       string        `json:"repo_url"`
+}
+
 type Option struct {
 	Name                 string
 	SnapshotId           string
 	UserName             string
 	Password             string
+	PasswordProvider     storage.PasswordProvider
 	CloudName            string
 	CloudRegion          string
 	DownloadPath         string
 	CloudApiMirror       string
+	CloudEndpoint        string
 	LimitDownloadRate    string
 	StorageTokenDuration string
+	Timeout              time.Duration
+	Progress             restic.ProgressFunc
+	Backend              storage.Backend
+	RestoreOptions       *restic.RestoreOptions
 }
 
-func (d *Download) Download(opt Option) error {
-	ctx, cancel := context.WithCancel(context.TODO())
-	defer cancel()
+func (d *Download) Download(ctx context.Context, opt Option) (*Result, error) {
 	d.option = opt
+
+	var cancel context.CancelFunc
+	if d.option.Timeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, d.option.Timeout)
+	} else {
+		ctx, cancel = context.WithCancel(ctx)
+	}
+	defer cancel()
+
 	var storageClient = &storage.StorageClient{
 		Name:                 d.option.Name,
 		SnapshotId:           d.option.SnapshotId,
 		UserName:             d.option.UserName,
 		Password:             d.option.Password,
+		PasswordProvider:     d.option.PasswordProvider,
 		CloudName:            d.option.CloudName,
 		CloudRegion:          d.option.CloudRegion,
 		DownloadPath:         d.option.DownloadPath,
 		CloudApiMirror:       d.option.CloudApiMirror,
+		CloudEndpoint:        d.option.CloudEndpoint,
 		LimitDownloadRate:    d.option.LimitDownloadRate,
 		StorageTokenDuration: d.option.StorageTokenDuration,
+		Progress:             d.option.Progress,
+		Backend:              d.option.Backend,
+		RestoreOptions:       d.option.RestoreOptions,
 	}
 
 	var (
-		err     error
-		exitCh  = make(chan *storage.StorageResponse)
-		summary *restic.RestoreSummaryOutput
+		exitCh = make(chan *storage.StorageResponse, 1)
+		e      *storage.StorageResponse
 	)
 
 	go storageClient.Download(ctx, exitCh)
 
 	select {
-	case e, ok := <-exitCh:
-		if ok && e.Error != nil {
-			err = e.Error
-		}
-		summary = e.RestoreSummary
+	case e = <-exitCh:
 	case <-ctx.Done():
-		err = errors.Errorf("restore %q osdata timed out in 2 hour", d.option.Name)
+		// restic is interrupted, wait until it exited so that it no longer
+		// writes to DownloadPath once Download returned
+		e = <-exitCh
+		if e.Error != nil {
+			if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+				return nil, errors.Wrapf(ctx.Err(), "restore %q osdata timed out", d.option.Name)
+			}
+			return nil, errors.Wrapf(ctx.Err(), "restore %q osdata canceled", d.option.Name)
+		}
 	}
 
-	if err != nil {
-		return err
+	if e.Error != nil {
+		return nil, e.Error
 	}
 
-	if summary != nil {
-		logger.Infof("download successful, data: %s", util.ToJSON(summary))
+	var summary, repoUrl = e.RestoreSummary, e.RepoUrl
+
+	if summary == nil {
+		return nil, errors.Errorf("restore %q osdata finished without summary", d.option.Name)
 	}
 
-	return nil
+	logger.Infof("download successful, data: %s", util.ToJSON(summary))
+
+	return &Result{
+		SnapshotId:    d.option.SnapshotId,
+		TotalFiles:    summary.TotalFiles,
+		FilesRestored: summary.FilesRestored,
+		FilesSkipped:  summary.FilesSkipped,
+		TotalBytes:    summary.TotalBytes,
+		BytesRestored: summary.BytesRestored,
+		BytesSkipped:  summary.BytesSkipped,
+		Duration:      time.Duration(summary.SecondsElapsed) * time.Second,
+		RepoUrl:       repoUrl,
+	}, nil
 }
@@ -0,0 +1,133 @@
+package restic
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// BackupOptions selects the files of a backup besides its upload path, and
+// sets the metadata of the new snapshot. Backups of stdin only use the
+// metadata.
+type BackupOptions struct {
+	// Paths are backed up along with the upload path.
+	Paths []string
+	// FilesFrom are files listing the paths to back up, one per line.
+	FilesFrom []string
+	// Excludes are patterns of the files to skip, e.g. "*.tmp" or
+	// "/var/cache".
+	Excludes []string
+	// ExcludeFiles are files listing exclude patterns, one per line.
+	ExcludeFiles []string
+	// ExcludeCaches skips the directories holding a CACHEDIR.TAG file.
+	ExcludeCaches bool
+	// ExcludeIfPresent skips the directories holding one of these files,
+	// given as "filename[:header]".
+	ExcludeIfPresent []string
+	// ExcludeLargerThan skips the files larger than this size, e.g. "2G".
+	ExcludeLargerThan string
+	// OneFileSystem does not cross file system boundaries.
+	OneFileSystem bool
+
+	// Tags are added to the name=<name> tag of the snapshot.
+	Tags []string
+	// Metadata is added as key=value tags, e.g. the SDK version, the user or
+	// the cluster the backup was made from.
+	Metadata map[string]string
+	// Host replaces the hostname restic records. Pods get a new hostname on
+	// every start, and restic only picks a snapshot of the same host as the
+	// parent, so without a stable host every backup rereads all files.
+	Host string
+	// Parent is the id of the snapshot to compare the files against, restic
+	// picks the latest snapshot of the same host and paths when empty.
+	Parent string
+	// Time is the time of the snapshot instead of the start of the backup.
+	Time time.Time
+}
+
+// snapshotArgs returns the arguments setting the metadata of the snapshot,
+// they apply to stdin backups as well.
+func (o *BackupOptions) snapshotArgs() ([]string, error) {
+	if o == nil {
+		return nil, nil
+	}
+
+	var tags = append([]string{}, o.Tags...)
+	var keys = make([]string, 0, len(o.Metadata))
+	for k := range o.Metadata {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		tags = append(tags, fmt.Sprintf("%s=%s", k, o.Metadata[k]))
+	}
+
+	var args []string
+	for _, tag := range tags {
+		if err := validTag(tag); err != nil {
+			return nil, err
+		}
+		args = append(args, "--tag", tag)
+	}
+	if o.Host != "" {
+		args = append(args, "--host", o.Host)
+	}
+	if o.Parent != "" {
+		args = append(args, "--parent", o.Parent)
+	}
+	if !o.Time.IsZero() {
+		args = append(args, "--time", o.Time.Local().Format(timeLayout))
+	}
+
+	return args, nil
+}
+
+// validTag rejects tags restic would split and the name tag the SDK sets.
+func validTag(tag string) error {
+	switch {
+	case tag == "" || strings.Contains(tag, ","):
+		return fmt.Errorf("invalid snapshot tag %q", tag)
+	case strings.HasPrefix(tag, nameTag("")):
+		return fmt.Errorf("snapshot tag %q is reserved", tag)
+	}
+	return nil
+}
+
+func (o *BackupOptions) args() []string {
+	if o == nil {
+		return nil
+	}
+
+	var args []string
+	for _, f := range o.FilesFrom {
+		args = append(args, "--files-from", f)
+	}
+	for _, e := range o.Excludes {
+		args = append(args, "--exclude", e)
+	}
+	for _, f := range o.ExcludeFiles {
+		args = append(args, "--exclude-file", f)
+	}
+	if o.ExcludeCaches {
+		args = append(args, "--exclude-caches")
+	}
+	for _, f := range o.ExcludeIfPresent {
+		args = append(args, "--exclude-if-present", f)
+	}
+	if o.ExcludeLargerThan != "" {
+		args = append(args, "--exclude-larger-than", o.ExcludeLargerThan)
+	}
+	if o.OneFileSystem {
+		args = append(args, "--one-file-system")
+	}
+
+	return args
+}
+
+func (o *BackupOptions) paths() []string {
+	if o == nil {
+		return nil
+	}
+	return o.Paths
+}
@@ -0,0 +1,165 @@
+package restic
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"bytetrade.io/web3os/uploader-sdk/pkg/util/logger"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestBackupOptionsSnapshotArgs(t *testing.T) {
+	tests := []struct {
+		name    string
+		opts    *BackupOptions
+		want    []string
+		wantErr bool
+	}{
+		{
+			name: "nil",
+			opts: nil,
+		},
+		{
+			name: "tags and metadata",
+			opts: &BackupOptions{
+				Tags:     []string{"daily"},
+				Metadata: map[string]string{"user": "alice", "cluster": "c1"},
+				Host:     "olares",
+				Parent:   "abcd1234",
+			},
+			want: []string{"--tag", "daily", "--tag", "cluster=c1", "--tag", "user=alice", "--host", "olares", "--parent", "abcd1234"},
+		},
+		{
+			name:    "comma in tag",
+			opts:    &BackupOptions{Tags: []string{"a,b"}},
+			wantErr: true,
+		},
+		{
+			name:    "reserved name tag",
+			opts:    &BackupOptions{Metadata: map[string]string{"name": "other"}},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.opts.snapshotArgs()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("snapshotArgs() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("snapshotArgs() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBackupOptionsArgs(t *testing.T) {
+	tests := []struct {
+		name      string
+		opts      *BackupOptions
+		wantArgs  []string
+		wantPaths []string
+	}{
+		{
+			name: "nil",
+			opts: nil,
+		},
+		{
+			name: "paths and excludes",
+			opts: &BackupOptions{
+				Paths:             []string{"/etc/app", "/var/lib/app"},
+				FilesFrom:         []string{"/tmp/files.txt"},
+				Excludes:          []string{"*.tmp", "/var/lib/app/cache"},
+				ExcludeFiles:      []string{"/tmp/excludes.txt"},
+				ExcludeCaches:     true,
+				ExcludeIfPresent:  []string{".nobackup"},
+				ExcludeLargerThan: "2G",
+				OneFileSystem:     true,
+			},
+			wantArgs: []string{
+				"--files-from", "/tmp/files.txt",
+				"--exclude", "*.tmp", "--exclude", "/var/lib/app/cache",
+				"--exclude-file", "/tmp/excludes.txt",
+				"--exclude-caches",
+				"--exclude-if-present", ".nobackup",
+				"--exclude-larger-than", "2G",
+				"--one-file-system",
+			},
+			wantPaths: []string{"/etc/app", "/var/lib/app"},
+		},
+		{
+			name:      "metadata only",
+			opts:      &BackupOptions{Tags: []string{"daily"}, Host: "olares"},
+			wantArgs:  nil,
+			wantPaths: nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.opts.args(); !reflect.DeepEqual(got, tt.wantArgs) {
+				t.Errorf("args() = %q, want %q", got, tt.wantArgs)
+			}
+			if got := tt.opts.paths(); !reflect.DeepEqual(got, tt.wantPaths) {
+				t.Errorf("paths() = %q, want %q", got, tt.wantPaths)
+			}
+		})
+	}
+}
+
+func TestBackupFileErrors(t *testing.T) {
+	// restic 0.17 backup --json of /data with one unreadable file
+	var stdout = `{"message_type":"status","percent_done":0,"total_files":3,"total_bytes":2048}
+{"message_type":"summary","files_new":2,"files_changed":0,"files_unmodified":0,"dirs_new":1,"dirs_changed":0,"dirs_unmodified":0,"data_blobs":2,"tree_blobs":1,"data_added":2048,"total_files_processed":2,"total_bytes_processed":2048,"total_duration":0.5,"snapshot_id":"4bb8d4cb0b3a7c5e9f1d2a6b8c0e4f7a9b1d3c5e7f9a0b2c4d6e8f0a1b3c5d7e"}
+`
+	var stderr = `{"message_type":"error","error":{"message":"open /data/secret: permission denied"},"during":"archival","item":"/data/secret"}
+Warning: at least one source file could not be read
+`
+
+	tests := []struct {
+		name      string
+		policy    ErrorPolicy
+		stdout    string
+		code      int
+		wantErr   bool
+		wantKind  error
+		wantSaved bool
+		wantWarns int
+	}{
+		{name: "warn", policy: ErrorPolicyWarn, stdout: stdout, code: exitCodeIncomplete, wantSaved: true, wantWarns: 1},
+		{name: "fail", policy: ErrorPolicyFail, stdout: stdout, code: exitCodeIncomplete, wantErr: true, wantKind: ErrIncompleteSnapshot, wantSaved: true, wantWarns: 1},
+		{name: "ignore", policy: ErrorPolicyIgnore, stdout: stdout, code: exitCodeIncomplete, wantSaved: true},
+		{name: "no snapshot", policy: ErrorPolicyWarn, code: exitCodeIncomplete, wantErr: true, wantWarns: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			core, logs := observer.New(zapcore.WarnLevel)
+			logger.SetLogger(zap.New(core).Sugar())
+			t.Cleanup(func() { logger.SetLogger(zap.NewNop().Sugar()) })
+
+			var r = fakeRestic(t, tt.stdout, stderr, tt.code)
+			r.opt.ErrorPolicy = tt.policy
+			summary, err := r.Backup("backup", "/data", nil, "")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Backup() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantKind != nil && !errors.Is(err, tt.wantKind) {
+				t.Fatalf("Backup() error = %v, want %v", err, tt.wantKind)
+			}
+			if n := logs.Len(); n != tt.wantWarns {
+				t.Errorf("Backup() logged %d warnings, want %d", n, tt.wantWarns)
+			}
+			if !tt.wantSaved {
+				if summary != nil {
+					t.Errorf("Backup() = %+v, want no summary", summary)
+				}
+				return
+			}
+			if summary == nil || !summary.Incomplete || len(summary.FileErrors) != 1 || summary.FileErrors[0].Item != "/data/secret" {
+				t.Fatalf("Backup() = %+v, want an incomplete summary missing /data/secret", summary)
+			}
+		})
+	}
+}
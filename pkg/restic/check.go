@@ -0,0 +1,106 @@
+package restic
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+
+	"bytetrade.io/web3os/uploader-sdk/pkg/util/logger"
+)
+
+const ERROR_MESSAGE_REPOSITORY_CONTAINS_ERRORS RESTIC_ERROR_MESSAGE = "repository contains errors"
+
+type CheckOptions struct {
+	// ReadData verifies the content of every pack file, which downloads the
+	// whole repository.
+	ReadData bool
+	// ReadDataSubset verifies a part of the pack files, e.g. "10%", "1/5"
+	// or "500M". It is ignored when ReadData is set.
+	ReadDataSubset string
+}
+
+func (o *CheckOptions) args() []string {
+	switch {
+	case o == nil:
+		return nil
+	case o.ReadData:
+		return []string{"--read-data"}
+	case o.ReadDataSubset != "":
+		return []string{"--read-data-subset", o.ReadDataSubset}
+	}
+	return nil
+}
+
+// CheckReport collects the problems restic check found in the repository.
+type CheckReport struct {
+	Errors             []string `json:"errors"`
+	BrokenPacks        []string `json:"broken_packs,omitempty"`
+	SuggestRepairIndex bool     `json:"suggest_repair_index"`
+	SuggestPrune       bool     `json:"suggest_prune"`
+}
+
+func (c *CheckReport) HasErrors() bool {
+	return len(c.Errors) > 0 || len(c.BrokenPacks) > 0
+}
+
+// Check verifies the repository. restic exits with code 1 when it found
+// problems, they are returned in the report rather than as an error.
+func (r *resticManager) Check(opts *CheckOptions) (*CheckReport, error) {
+	var args = []string{
+		"check",
+		PARAM_JSON_OUTPUT,
+		PARAM_INSECURE_TLS,
+	}
+	args = append(args, opts.args()...)
+
+	var report = &CheckReport{}
+	var inError bool
+	// reported is set once restic printed its result
+	var reported bool
+	err := r.run(args, func(res []byte) error {
+		var msg = string(res)
+		logger.Debugf("[restic] check %s message: %s", r.name, msg)
+
+		var out CheckOutput
+		if err := json.Unmarshal(res, &out); err == nil {
+			switch out.MessageType {
+			case "error":
+				report.Errors = append(report.Errors, out.Message)
+				reported = true
+			case "summary":
+				reported = true
+				report.BrokenPacks = out.BrokenPacks
+				report.SuggestRepairIndex = out.SuggestRepairIndex
+				report.SuggestPrune = out.SuggestPrune
+			}
+			return nil
+		}
+
+		// restic before 0.17 prints the check result as plain text
+		switch {
+		case strings.Contains(msg, ERROR_MESSAGE_REPOSITORY_CONTAINS_ERRORS.Error()):
+			reported = true
+			return nil
+		case strings.Contains(msg, "Fatal: "):
+			return classify(msg)
+		case strings.HasPrefix(msg, "error"), strings.Contains(msg, "not referenced in any index"):
+			report.Errors = append(report.Errors, strings.TrimSpace(msg))
+			inError = strings.HasSuffix(msg, ":")
+			reported = true
+		case inError && strings.HasPrefix(msg, " "):
+			report.Errors = append(report.Errors, strings.TrimSpace(msg))
+		default:
+			inError = false
+		}
+		return nil
+	})
+	var resticErr *Error
+	if errors.As(err, &resticErr) && resticErr.ExitCode == exitCodeFailure && reported {
+		return report, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return report, nil
+}
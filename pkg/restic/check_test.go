@@ -0,0 +1,81 @@
+package restic
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestCheck(t *testing.T) {
+	tests := []struct {
+		name    string
+		stdout  string
+		stderr  string
+		code    int
+		want    *CheckReport
+		wantErr bool
+		// wantKind is the failure class of the error
+		wantKind error
+	}{
+		{
+			name:   "no errors",
+			stdout: `{"message_type":"summary","num_errors":0,"suggest_repair_index":false,"suggest_prune":false}` + "\n",
+			want:   &CheckReport{},
+		},
+		{
+			name:   "errors found",
+			stdout: `{"message_type":"summary","num_errors":1,"broken_packs":["2bd3a4c5"],"suggest_repair_index":false,"suggest_prune":true}` + "\n",
+			stderr: `{"message_type":"error","message":"pack 2bd3a4c5: not referenced in any index"}` + "\n" +
+				"Fatal: repository contains errors\n",
+			code: 1,
+			want: &CheckReport{
+				Errors:       []string{"pack 2bd3a4c5: not referenced in any index"},
+				BrokenPacks:  []string{"2bd3a4c5"},
+				SuggestPrune: true,
+			},
+		},
+		{
+			name: "errors found before restic 0.17",
+			stdout: "using temporary cache in /tmp/restic-check-cache-1\n" +
+				"check snapshots, trees and blobs\n" +
+				"error for tree 4bb8d4cb:\n" +
+				"  id 4bb8d4cb not found in repository\n" +
+				"[0:00] 100.00%  1 / 1 snapshots\n",
+			stderr: "Fatal: repository contains errors\n",
+			code:   1,
+			want: &CheckReport{
+				Errors: []string{"error for tree 4bb8d4cb:", "id 4bb8d4cb not found in repository"},
+			},
+		},
+		{
+			name:     "locked",
+			stderr:   "Fatal: unable to create lock in backend: repository is already locked by PID 12 on host\n",
+			code:     11,
+			wantErr:  true,
+			wantKind: ErrRepoLocked,
+		},
+		{
+			name:    "failed without result",
+			stderr:  "unexpected failure\n",
+			code:    1,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := fakeRestic(t, tt.stdout, tt.stderr, tt.code).Check(nil)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Check() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantKind != nil && !errors.Is(err, tt.wantKind) {
+				t.Errorf("Check() error = %v, want %v", err, tt.wantKind)
+			}
+			if tt.wantErr {
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Check() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
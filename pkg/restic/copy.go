@@ -0,0 +1,83 @@
+package restic
+
+import (
+	"regexp"
+	"strings"
+
+	"bytetrade.io/web3os/uploader-sdk/pkg/util/logger"
+)
+
+var (
+	// restic copy prints no json, these match its text output, the skipped
+	// snapshots are only printed with -v
+	copySourceRegexp  = regexp.MustCompile(`^snapshot ([0-9a-f]+) of \[`)
+	copySavedRegexp   = regexp.MustCompile(`snapshot ([0-9a-f]+) saved`)
+	copySkippedRegexp = regexp.MustCompile(`skipping source snapshot ([0-9a-f]+), was already copied to snapshot ([0-9a-f]+)`)
+)
+
+// CopyReport maps the short ids of the source snapshots to the short ids of
+// their copies in the destination repository.
+type CopyReport struct {
+	Copied map[string]string `json:"copied"`
+	// Skipped are the snapshots the destination already had a copy of.
+	Skipped map[string]string `json:"skipped"`
+}
+
+func NewCopyReport() *CopyReport {
+	return &CopyReport{
+		Copied:  make(map[string]string),
+		Skipped: make(map[string]string),
+	}
+}
+
+// Copy copies the snapshots snapshotIds from the repository set in
+// RESTIC_FROM_REPOSITORY to the repository of r. Progress is reported once
+// per snapshot, with TotalFiles and FilesDone counting snapshots.
+func (r *resticManager) Copy(snapshotIds []string) (*CopyReport, error) {
+	var args = []string{
+		"copy",
+		"-v",
+		PARAM_INSECURE_TLS,
+	}
+	args = append(args, snapshotIds...)
+
+	var report = NewCopyReport()
+	var source string
+	var progress = func() {
+		var done = len(report.Copied) + len(report.Skipped)
+		r.opt.Progress.Report(&ProgressEvent{
+			Phase:       PhaseCopy,
+			PercentDone: float64(done) / float64(len(snapshotIds)),
+			TotalFiles:  uint64(len(snapshotIds)),
+			FilesDone:   uint64(done),
+		})
+	}
+	err := r.run(args, func(res []byte) error {
+		var msg = strings.TrimSpace(string(res))
+		logger.Debugf("[restic] copy %s message: %s", r.name, msg)
+
+		switch {
+		case strings.Contains(msg, "Fatal: "):
+			return classify(msg)
+		case copySkippedRegexp.MatchString(msg):
+			var m = copySkippedRegexp.FindStringSubmatch(msg)
+			report.Skipped[m[1]] = m[2]
+			source = ""
+			progress()
+		case copySourceRegexp.MatchString(msg):
+			source = copySourceRegexp.FindStringSubmatch(msg)[1]
+		case copySavedRegexp.MatchString(msg) && source != "":
+			var saved = copySavedRegexp.FindStringSubmatch(msg)[1]
+			logger.Infof("[restic] copy %s snapshot %s saved as %s", r.name, source, saved)
+			report.Copied[source] = saved
+			source = ""
+			progress()
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return report, nil
+}
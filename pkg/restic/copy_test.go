@@ -0,0 +1,41 @@
+package restic
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCopy(t *testing.T) {
+	// restic 0.17 copy -v of a snapshot copied before and a new one
+	var stdout = `repository 1a2b3c4d opened (version 2, compression level auto)
+[0:00] 100.00%  1 / 1 index files loaded
+repository 9f8e7d6c opened (version 2, compression level auto)
+[0:00] 100.00%  1 / 1 index files loaded
+
+snapshot 4bb8d4cb of [/data] at 2024-09-01 10:00:00.000000000 +0000 UTC by root@olares
+skipping source snapshot 4bb8d4cb, was already copied to snapshot 7e6f5a4b
+
+snapshot 2c3d4e5f of [/data] at 2024-09-02 10:00:00.000000000 +0000 UTC by root@olares
+  copy started, this may take a while...
+[0:01] 100.00%  3 / 3 packs copied
+snapshot 8a9b0c1d saved
+`
+
+	var r = fakeRestic(t, stdout, "", 0)
+	report, err := r.Copy([]string{"4bb8d4cb", "2c3d4e5f"})
+	if err != nil {
+		t.Fatalf("Copy() error = %v", err)
+	}
+
+	var want = &CopyReport{
+		Copied:  map[string]string{"2c3d4e5f": "8a9b0c1d"},
+		Skipped: map[string]string{"4bb8d4cb": "7e6f5a4b"},
+	}
+	if !reflect.DeepEqual(report, want) {
+		t.Errorf("Copy() = %+v, want %+v", report, want)
+	}
+	var wantArgs = []string{"copy", "-v", PARAM_INSECURE_TLS, "4bb8d4cb", "2c3d4e5f"}
+	if args := fakeArgs(t, r); !reflect.DeepEqual(args, wantArgs) {
+		t.Errorf("args = %q, want %q", args, wantArgs)
+	}
+}
@@ -0,0 +1,125 @@
+package restic
+
+import (
+	"encoding/json"
+	"strings"
+
+	"bytetrade.io/web3os/uploader-sdk/pkg/util/logger"
+)
+
+// DiffChange is a path that differs between two snapshots. Modifier is the
+// restic change code: "+" added, "-" removed, "M" content modified, "T" type
+// changed, "U" metadata changed and "?" content changed without its
+// modification time, a sign of bitrot.
+type DiffChange struct {
+	Path     string `json:"path"`
+	Modifier string `json:"modifier"`
+}
+
+type DiffStats struct {
+	Files     int    `json:"files"`
+	Dirs      int    `json:"dirs"`
+	Others    int    `json:"others"`
+	DataBlobs int    `json:"data_blobs"`
+	TreeBlobs int    `json:"tree_blobs"`
+	Bytes     uint64 `json:"bytes"`
+}
+
+// DiffReport lists what changed from the source to the target snapshot.
+type DiffReport struct {
+	SourceSnapshot string        `json:"source_snapshot"`
+	TargetSnapshot string        `json:"target_snapshot"`
+	Added          []string      `json:"added"`
+	Removed        []string      `json:"removed"`
+	Modified       []*DiffChange `json:"modified"`
+	ChangedFiles   int           `json:"changed_files"`
+	AddedStats     *DiffStats    `json:"added_stats"`
+	RemovedStats   *DiffStats    `json:"removed_stats"`
+}
+
+// ChangeRatio is the share of the totalFiles of the target snapshot whose
+// content was modified or that were removed, e.g. with the
+// TotalFilesProcessed of its summary. A ratio close to 1 after a routine
+// backup hints at a mass modification such as ransomware encryption.
+// Directories, listed by restic with a trailing "/", are not counted.
+func (d *DiffReport) ChangeRatio(totalFiles int64) float64 {
+	if totalFiles <= 0 {
+		return 0
+	}
+
+	var changed int
+	for _, p := range d.Removed {
+		if !strings.HasSuffix(p, "/") {
+			changed++
+		}
+	}
+	for _, c := range d.Modified {
+		if !strings.HasSuffix(c.Path, "/") && strings.ContainsAny(c.Modifier, "MT?") {
+			changed++
+		}
+	}
+	return float64(changed) / float64(totalFiles)
+}
+
+// diffOutput is a line of restic diff --json, a "change" per path followed
+// by the "statistics".
+type diffOutput struct {
+	MessageType    string     `json:"message_type"`
+	Path           string     `json:"path"`
+	Modifier       string     `json:"modifier"`
+	SourceSnapshot string     `json:"source_snapshot"`
+	TargetSnapshot string     `json:"target_snapshot"`
+	ChangedFiles   int        `json:"changed_files"`
+	Added          *DiffStats `json:"added"`
+	Removed        *DiffStats `json:"removed"`
+}
+
+func (r *resticManager) Diff(snapA string, snapB string) (*DiffReport, error) {
+	var args = []string{
+		"diff",
+		PARAM_JSON_OUTPUT,
+		PARAM_INSECURE_TLS,
+		snapA,
+		snapB,
+	}
+
+	var report = &DiffReport{
+		SourceSnapshot: snapA,
+		TargetSnapshot: snapB,
+	}
+	err := r.run(args, func(res []byte) error {
+		var out diffOutput
+		if err := json.Unmarshal(res, &out); err != nil {
+			var msg = string(res)
+			logger.Debugf("[restic] diff %s message: %s", r.name, msg)
+			if strings.Contains(msg, "Fatal: ") {
+				return classify(msg)
+			}
+			return nil
+		}
+
+		switch out.MessageType {
+		case "change":
+			switch out.Modifier {
+			case "+":
+				report.Added = append(report.Added, out.Path)
+			case "-":
+				report.Removed = append(report.Removed, out.Path)
+			default:
+				report.Modified = append(report.Modified, &DiffChange{Path: out.Path, Modifier: out.Modifier})
+			}
+		case "statistics":
+			report.SourceSnapshot = out.SourceSnapshot
+			report.TargetSnapshot = out.TargetSnapshot
+			report.ChangedFiles = out.ChangedFiles
+			report.AddedStats = out.Added
+			report.RemovedStats = out.Removed
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return report, nil
+}
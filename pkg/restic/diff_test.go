@@ -0,0 +1,89 @@
+package restic
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDiff(t *testing.T) {
+	var stdout = `{"message_type":"change","path":"/data/new.txt","modifier":"+"}
+{"message_type":"change","path":"/data/notes.txt","modifier":"M"}
+{"message_type":"change","path":"/data/old/","modifier":"-"}
+{"message_type":"change","path":"/data/old/a.txt","modifier":"-"}
+{"message_type":"change","path":"/data/old/b.txt","modifier":"-"}
+{"message_type":"change","path":"/data/photos/","modifier":"U"}
+{"message_type":"change","path":"/data/photos/cat.jpg","modifier":"U"}
+{"message_type":"change","path":"/data/link","modifier":"T"}
+{"message_type":"statistics","source_snapshot":"4bb8d4cb0a1f","target_snapshot":"9e0a6f31c2d4","changed_files":5,"added":{"files":1,"dirs":0,"others":0,"data_blobs":2,"tree_blobs":2,"bytes":2048},"removed":{"files":3,"dirs":1,"others":0,"data_blobs":3,"tree_blobs":2,"bytes":4096}}
+`
+	report, err := fakeRestic(t, stdout, "", 0).Diff("4bb8d4cb", "9e0a6f31")
+	if err != nil {
+		t.Fatalf("Diff() error = %v", err)
+	}
+
+	var want = &DiffReport{
+		SourceSnapshot: "4bb8d4cb0a1f",
+		TargetSnapshot: "9e0a6f31c2d4",
+		Added:          []string{"/data/new.txt"},
+		Removed:        []string{"/data/old/", "/data/old/a.txt", "/data/old/b.txt"},
+		Modified: []*DiffChange{
+			{Path: "/data/notes.txt", Modifier: "M"},
+			{Path: "/data/photos/", Modifier: "U"},
+			{Path: "/data/photos/cat.jpg", Modifier: "U"},
+			{Path: "/data/link", Modifier: "T"},
+		},
+		ChangedFiles: 5,
+		AddedStats:   &DiffStats{Files: 1, DataBlobs: 2, TreeBlobs: 2, Bytes: 2048},
+		RemovedStats: &DiffStats{Files: 3, Dirs: 1, DataBlobs: 3, TreeBlobs: 2, Bytes: 4096},
+	}
+	if !reflect.DeepEqual(report, want) {
+		t.Errorf("Diff() = %+v, want %+v", report, want)
+	}
+}
+
+func TestDiffChangeRatio(t *testing.T) {
+	tests := []struct {
+		name       string
+		report     *DiffReport
+		totalFiles int64
+		want       float64
+	}{
+		{
+			name:       "no files",
+			report:     &DiffReport{Removed: []string{"/data/a.txt"}},
+			totalFiles: 0,
+			want:       0,
+		},
+		{
+			name: "removed and modified files",
+			report: &DiffReport{
+				Added:   []string{"/data/new.txt"},
+				Removed: []string{"/data/old/", "/data/old/a.txt"},
+				Modified: []*DiffChange{
+					{Path: "/data/notes.txt", Modifier: "M"},
+					{Path: "/data/link", Modifier: "T"},
+					{Path: "/data/photo.jpg", Modifier: "?"},
+					{Path: "/data/photos/", Modifier: "U"},
+					{Path: "/data/cat.jpg", Modifier: "U"},
+				},
+			},
+			totalFiles: 10,
+			want:       0.4,
+		},
+		{
+			name: "removed directories only",
+			report: &DiffReport{
+				Removed: []string{"/data/old/", "/data/empty/"},
+			},
+			totalFiles: 10,
+			want:       0,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.report.ChangeRatio(tt.totalFiles); got != tt.want {
+				t.Errorf("ChangeRatio() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,66 @@
+package restic
+
+import (
+	"context"
+	"io"
+	"path"
+
+	"bytetrade.io/web3os/uploader-sdk/pkg/util/cmd"
+)
+
+// ArchiveFormat is the format restic dump packs a directory in.
+type ArchiveFormat string
+
+const (
+	ArchiveTar ArchiveFormat = "tar"
+	ArchiveZip ArchiveFormat = "zip"
+)
+
+// Dump streams the content of the file p of the snapshot, a directory is
+// streamed as a tar archive. p is an absolute path as backed up. The command
+// stops when ctx is done or the reader is closed, and a restic failure is
+// returned by Read as an *Error.
+func (r *resticManager) Dump(ctx context.Context, snapshotId string, p string) (io.ReadCloser, error) {
+	return r.DumpArchive(ctx, snapshotId, p, "")
+}
+
+// DumpArchive is Dump with the archive format of directories, tar when empty.
+func (r *resticManager) DumpArchive(ctx context.Context, snapshotId string, p string, archive ArchiveFormat) (io.ReadCloser, error) {
+	var args = []string{
+		"dump",
+		PARAM_INSECURE_TLS,
+	}
+	if archive != "" {
+		args = append(args, "--archive", string(archive))
+	}
+	args = append(args, snapshotId, path.Clean("/"+p))
+
+	c := cmd.NewCommand(ctx, cmd.CommandOptions{
+		Path: r.bin,
+		Args: args,
+		Envs: r.envs,
+	})
+	rc, err := c.Stream()
+	if err != nil {
+		return nil, err
+	}
+
+	return &dumpReader{rc}, nil
+}
+
+// dumpReader returns the failures of restic dump as *Error.
+type dumpReader struct {
+	io.ReadCloser
+}
+
+func (d *dumpReader) Read(p []byte) (int, error) {
+	n, err := d.ReadCloser.Read(p)
+	if err != nil && err != io.EOF {
+		err = fromExitError(err)
+	}
+	return n, err
+}
+
+func (d *dumpReader) Close() error {
+	return fromExitError(d.ReadCloser.Close())
+}
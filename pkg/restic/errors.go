@@ -0,0 +1,84 @@
+package restic
+
+import (
+	"errors"
+	"strings"
+)
+
+// Failure classes of restic commands, match them with errors.Is.
+var (
+	ErrTokenExpired           = errors.New("storage token expired")
+	ErrRepoLocked             = errors.New("repository is locked")
+	ErrRepoNotFound           = errors.New("repository not found")
+	ErrRepoAlreadyInitialized = errors.New("repository already initialized")
+	ErrSnapshotNotFound       = errors.New("snapshot not found")
+	ErrWrongPassword          = errors.New("wrong repository password")
+	ErrNetwork                = errors.New("network error")
+	ErrQuotaExceeded          = errors.New("storage quota exceeded")
+	// ErrIncompleteSnapshot is returned under ErrorPolicyFail when restic
+	// saved the snapshot but could not read some of the files (exit code 3).
+	ErrIncompleteSnapshot = errors.New("snapshot created, but some files could not be read")
+)
+
+// Error is a failure reported by restic. Kind is one of the failure classes
+// above, or nil when the message is not recognized. ExitCode is the exit
+// code of restic, or 0 when the failure was detected in its output.
+type Error struct {
+	Kind     error
+	Message  string
+	ExitCode int
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+func (e *Error) Unwrap() error {
+	return e.Kind
+}
+
+var errorPatterns = []struct {
+	kind     error
+	messages []string
+}{
+	{ErrTokenExpired, []string{ERROR_MESSAGE_TOKEN_EXPIRED.Error(), "ExpiredToken"}},
+	{ErrRepoLocked, []string{ERROR_MESSAGE_LOCKED.Error(), "unable to create lock"}},
+	{ErrRepoAlreadyInitialized, []string{ERROR_MESSAGE_ALREADY_INITIALIZED.Error(), "config file already exists"}},
+	{ErrSnapshotNotFound, []string{"no matching ID found", "failed to find snapshot", "no snapshot found"}},
+	{ErrWrongPassword, []string{"wrong password or no key found"}},
+	{ErrRepoNotFound, []string{"repository does not exist", "Is there a repository at the following location?", "NoSuchBucket", "The specified bucket does not exist"}},
+	{ErrQuotaExceeded, []string{"QuotaExceeded", "quota exceeded", "no space left on device"}},
+	{ErrNetwork, []string{"connection refused", "no such host", "i/o timeout", "connection reset by peer", "network is unreachable", "TLS handshake timeout"}},
+}
+
+// Exit codes of restic 0.17, documented in "Scripting" of the restic manual.
+const (
+	exitCodeFailure    = 1
+	exitCodeIncomplete = 3
+)
+
+// exitCodeKinds maps the exit codes restic reserves for a failure class.
+var exitCodeKinds = map[int]error{
+	10: ErrRepoNotFound,
+	11: ErrRepoLocked,
+	12: ErrWrongPassword,
+}
+
+// classify turns a restic error message into an *Error carrying its failure
+// class.
+func classify(msg string) *Error {
+	msg = strings.TrimSpace(msg)
+	for _, pattern := range errorPatterns {
+		for _, m := range pattern.messages {
+			if strings.Contains(msg, m) {
+				return &Error{Kind: pattern.kind, Message: msg}
+			}
+		}
+	}
+	return &Error{Message: msg}
+}
+
+// newError returns an *Error of kind with the restic message msg.
+func newError(kind error, msg string) *Error {
+	return &Error{Kind: kind, Message: strings.TrimSpace(msg)}
+}
@@ -0,0 +1,38 @@
+package restic
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestClassify(t *testing.T) {
+	tests := []struct {
+		msg  string
+		want error
+	}{
+		{"Fatal: unable to open repository: The provided token has expired.", ErrTokenExpired},
+		{"Fatal: unable to create lock in backend: repository is already locked by PID 1 on host", ErrRepoLocked},
+		{"Fatal: wrong password or no key found", ErrWrongPassword},
+		{"Fatal: unable to open config file: Stat: The specified bucket does not exist.\nIs there a repository at the following location?", ErrRepoNotFound},
+		{"Fatal: failed to find snapshot: no matching ID found for prefix \"abc\"", ErrSnapshotNotFound},
+		{"Save(<data/6f2a>) returned error: dial tcp: lookup s3.amazonaws.com: no such host", ErrNetwork},
+		{"Fatal: unable to save snapshot: QuotaExceeded: bucket quota exceeded", ErrQuotaExceeded},
+		{"Fatal: something unexpected", nil},
+	}
+
+	for _, tt := range tests {
+		err := fmt.Errorf("backup: %w", classify(tt.msg))
+		if tt.want != nil && !errors.Is(err, tt.want) {
+			t.Errorf("classify(%q) is not %v", tt.msg, tt.want)
+		}
+
+		var resticErr *Error
+		if !errors.As(err, &resticErr) {
+			t.Fatalf("classify(%q) is not a *Error", tt.msg)
+		}
+		if resticErr.Kind != tt.want {
+			t.Errorf("classify(%q).Kind = %v, want %v", tt.msg, resticErr.Kind, tt.want)
+		}
+	}
+}
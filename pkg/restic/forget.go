@@ -0,0 +1,128 @@
+package restic
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"bytetrade.io/web3os/uploader-sdk/pkg/util/logger"
+)
+
+// RetentionPolicy maps to the keep options of restic forget. Snapshots that
+// are not kept by any rule are removed, Prune also deletes their data.
+type RetentionPolicy struct {
+	KeepLast    int      `json:"keep_last,omitempty"`
+	KeepHourly  int      `json:"keep_hourly,omitempty"`
+	KeepDaily   int      `json:"keep_daily,omitempty"`
+	KeepWeekly  int      `json:"keep_weekly,omitempty"`
+	KeepMonthly int      `json:"keep_monthly,omitempty"`
+	KeepYearly  int      `json:"keep_yearly,omitempty"`
+	KeepWithin  string   `json:"keep_within,omitempty"` // e.g. "1y6m2d"
+	KeepTags    []string `json:"keep_tags,omitempty"`
+	// GroupBy lists what the rules are applied per, any of host, paths and
+	// tags. It defaults to paths: restic groups by host as well, but pods
+	// get a new hostname on every start, so every snapshot would be kept.
+	GroupBy string `json:"group_by,omitempty"`
+	Prune   bool   `json:"prune,omitempty"`
+}
+
+const defaultForgetGroupBy = "paths"
+
+func (p *RetentionPolicy) args() ([]string, error) {
+	var args []string
+	for _, keep := range []struct {
+		flag  string
+		count int
+	}{
+		{"--keep-last", p.KeepLast},
+		{"--keep-hourly", p.KeepHourly},
+		{"--keep-daily", p.KeepDaily},
+		{"--keep-weekly", p.KeepWeekly},
+		{"--keep-monthly", p.KeepMonthly},
+		{"--keep-yearly", p.KeepYearly},
+	} {
+		if keep.count > 0 {
+			args = append(args, keep.flag, strconv.Itoa(keep.count))
+		}
+	}
+	if p.KeepWithin != "" {
+		args = append(args, "--keep-within", p.KeepWithin)
+	}
+	for _, tag := range p.KeepTags {
+		args = append(args, "--keep-tag", tag)
+	}
+
+	if len(args) == 0 {
+		return nil, fmt.Errorf("retention policy is empty")
+	}
+	var groupBy = p.GroupBy
+	if groupBy == "" {
+		groupBy = defaultForgetGroupBy
+	}
+	args = append(args, "--group-by", groupBy)
+	if p.Prune {
+		args = append(args, "--prune")
+	}
+
+	return args, nil
+}
+
+// ForgetReport lists what restic forget decided for every snapshot group.
+type ForgetReport struct {
+	Groups  []*ForgetGroup `json:"groups"`
+	Removed []*Snapshot    `json:"removed"`
+}
+
+func (r *resticManager) Forget(policy *RetentionPolicy) (*ForgetReport, error) {
+	if policy == nil {
+		return nil, fmt.Errorf("retention policy is empty")
+	}
+	policyArgs, err := policy.args()
+	if err != nil {
+		return nil, err
+	}
+
+	var args = []string{
+		"forget",
+		PARAM_JSON_OUTPUT,
+		PARAM_INSECURE_TLS,
+		"--tag",
+		nameTag(r.name),
+	}
+	args = append(args, policyArgs...)
+
+	var report = &ForgetReport{}
+	err = r.run(args, func(res []byte) error {
+		var msg = string(res)
+		logger.Debugf("[restic] forget %s message: %s", r.name, msg)
+		if strings.Contains(msg, "Fatal: ") {
+			return classify(msg)
+		}
+		if !strings.HasPrefix(msg, "[") {
+			// prune progress is printed as plain text
+			return nil
+		}
+		return json.Unmarshal(res, &report.Groups)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, group := range report.Groups {
+		report.Removed = append(report.Removed, group.Remove...)
+	}
+
+	return report, nil
+}
+
+func (r *resticManager) Prune() error {
+	return r.run([]string{"prune", PARAM_INSECURE_TLS}, func(res []byte) error {
+		var msg = string(res)
+		logger.Debugf("[restic] prune %s message: %s", r.name, msg)
+		if strings.Contains(msg, "Fatal: ") {
+			return classify(msg)
+		}
+		return nil
+	})
+}
@@ -0,0 +1,47 @@
+package restic
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestForget(t *testing.T) {
+	// restic forget --json --group-by paths, three snapshots made by pods
+	// with different hostnames.
+	var stdout = `[{"tags":null,"host":"","paths":["/data"],"keep":[{"time":"2024-09-03T10:00:00.000000000Z","tree":"c3","paths":["/data"],"tags":["name=backup"],"hostname":"backup-7d9f-x2k4q","username":"root","id":"c3c3c3c3c3c3c3c3c3c3c3c3c3c3c3c3c3c3c3c3c3c3c3c3c3c3c3c3c3c3c3c3","short_id":"c3c3c3c3"},{"time":"2024-09-02T10:00:00.000000000Z","tree":"b2","paths":["/data"],"tags":["name=backup"],"hostname":"backup-7d9f-m8p2z","username":"root","id":"b2b2b2b2b2b2b2b2b2b2b2b2b2b2b2b2b2b2b2b2b2b2b2b2b2b2b2b2b2b2b2b2","short_id":"b2b2b2b2"}],"remove":[{"time":"2024-09-01T10:00:00.000000000Z","tree":"a1","paths":["/data"],"tags":["name=backup"],"hostname":"backup-7d9f-q4w7r","username":"root","id":"a1a1a1a1a1a1a1a1a1a1a1a1a1a1a1a1a1a1a1a1a1a1a1a1a1a1a1a1a1a1a1a1","short_id":"a1a1a1a1"}],"reasons":[{"snapshot":{"time":"2024-09-03T10:00:00.000000000Z","tree":"c3","paths":["/data"],"tags":["name=backup"],"hostname":"backup-7d9f-x2k4q","username":"root","id":"c3c3c3c3c3c3c3c3c3c3c3c3c3c3c3c3c3c3c3c3c3c3c3c3c3c3c3c3c3c3c3c3","short_id":"c3c3c3c3"},"matches":["last snapshot"]},{"snapshot":{"time":"2024-09-02T10:00:00.000000000Z","tree":"b2","paths":["/data"],"tags":["name=backup"],"hostname":"backup-7d9f-m8p2z","username":"root","id":"b2b2b2b2b2b2b2b2b2b2b2b2b2b2b2b2b2b2b2b2b2b2b2b2b2b2b2b2b2b2b2b2","short_id":"b2b2b2b2"},"matches":["last snapshot"]}]}]` + "\n"
+
+	tests := []struct {
+		name     string
+		policy   *RetentionPolicy
+		wantArgs []string
+	}{
+		{
+			name:     "default group",
+			policy:   &RetentionPolicy{KeepLast: 2},
+			wantArgs: []string{"forget", PARAM_JSON_OUTPUT, PARAM_INSECURE_TLS, "--tag", "name=backup", "--keep-last", "2", "--group-by", "paths"},
+		},
+		{
+			name:     "custom group",
+			policy:   &RetentionPolicy{KeepLast: 2, GroupBy: "paths,tags"},
+			wantArgs: []string{"forget", PARAM_JSON_OUTPUT, PARAM_INSECURE_TLS, "--tag", "name=backup", "--keep-last", "2", "--group-by", "paths,tags"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var r = fakeRestic(t, stdout, "", 0)
+			report, err := r.Forget(tt.policy)
+			if err != nil {
+				t.Fatalf("Forget() error = %v", err)
+			}
+			if args := fakeArgs(t, r); !reflect.DeepEqual(args, tt.wantArgs) {
+				t.Errorf("args = %q, want %q", args, tt.wantArgs)
+			}
+			if len(report.Groups) != 1 || len(report.Groups[0].Keep) != 2 {
+				t.Fatalf("Forget() groups = %+v, want one group keeping 2 snapshots", report.Groups)
+			}
+			if len(report.Removed) != 1 || report.Removed[0].ShortId != "a1a1a1a1" {
+				t.Errorf("Forget() removed = %+v, want snapshot a1a1a1a1", report.Removed)
+			}
+		})
+	}
+}
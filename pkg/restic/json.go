@@ -1,6 +1,9 @@
 package restic
 
-import "fmt"
+import (
+	"fmt"
+	"time"
+)
 
 type StatusUpdate struct {
 	MessageType      string   `json:"message_type"` // "status"
@@ -59,12 +62,18 @@ type SummaryOutput struct {
 	TotalDuration       float64 `json:"total_duration"` // in seconds
 	SnapshotID          string  `json:"snapshot_id,omitempty"`
 	DryRun              bool    `json:"dry_run,omitempty"`
+
+	// Incomplete is set when the snapshot misses files restic could not
+	// read, they are listed in FileErrors.
+	Incomplete bool           `json:"incomplete,omitempty"`
+	FileErrors []*ErrorUpdate `json:"file_errors,omitempty"`
 }
 
 type Snapshot struct {
 	Time           string           `json:"time"`
 	Tree           string           `json:"tree"`
 	Paths          []string         `json:"paths"`
+	Tags           []string         `json:"tags,omitempty"`
 	Hostname       string           `json:"hostname"`
 	Username       string           `json:"username"`
 	ProgramVersion string           `json:"program_version"`
@@ -73,6 +82,10 @@ type Snapshot struct {
 	ShortId        string           `json:"short_id"`
 }
 
+func (s *Snapshot) GetTime() (time.Time, error) {
+	return time.Parse(time.RFC3339Nano, s.Time)
+}
+
 type SnapshotSummary struct {
 	BackupStart         string `json:"backup_start"`
 	BackupEnd           string `json:"backup_end"`
@@ -91,15 +104,16 @@ type SnapshotSummary struct {
 }
 
 type RestoreStatusUpdate struct {
-	MessageType    string  `json:"message_type"` // "status"
-	SecondsElapsed uint64  `json:"seconds_elapsed,omitempty"`
-	PercentDone    float64 `json:"percent_done"`
-	TotalFiles     uint64  `json:"total_files,omitempty"`
-	FilesRestored  uint64  `json:"files_restored,omitempty"`
-	FilesSkipped   uint64  `json:"files_skipped,omitempty"`
-	TotalBytes     uint64  `json:"total_bytes,omitempty"`
-	BytesRestored  uint64  `json:"bytes_restored,omitempty"`
-	BytesSkipped   uint64  `json:"bytes_skipped,omitempty"`
+	MessageType      string  `json:"message_type"` // "status"
+	SecondsElapsed   uint64  `json:"seconds_elapsed,omitempty"`
+	SecondsRemaining uint64  `json:"seconds_remaining,omitempty"`
+	PercentDone      float64 `json:"percent_done"`
+	TotalFiles       uint64  `json:"total_files,omitempty"`
+	FilesRestored    uint64  `json:"files_restored,omitempty"`
+	FilesSkipped     uint64  `json:"files_skipped,omitempty"`
+	TotalBytes       uint64  `json:"total_bytes,omitempty"`
+	BytesRestored    uint64  `json:"bytes_restored,omitempty"`
+	BytesSkipped     uint64  `json:"bytes_skipped,omitempty"`
 }
 
 func (s *RestoreStatusUpdate) GetPercentDone() string {
@@ -129,3 +143,26 @@ type InitSummaryOutput struct {
 	Id          string `json:"id"`
 	Repository  string `json:"repository"`
 }
+
+type ForgetGroup struct {
+	Tags    []string      `json:"tags"`
+	Host    string        `json:"host"`
+	Paths   []string      `json:"paths"`
+	Keep    []*Snapshot   `json:"keep"`
+	Remove  []*Snapshot   `json:"remove"`
+	Reasons []*KeepReason `json:"reasons"`
+}
+
+type KeepReason struct {
+	Snapshot *Snapshot `json:"snapshot"`
+	Matches  []string  `json:"matches"`
+}
+
+type CheckOutput struct {
+	MessageType        string   `json:"message_type"` // "error", "summary"
+	Message            string   `json:"message,omitempty"`
+	NumErrors          int      `json:"num_errors,omitempty"`
+	BrokenPacks        []string `json:"broken_packs,omitempty"`
+	SuggestRepairIndex bool     `json:"suggest_repair_index,omitempty"`
+	SuggestPrune       bool     `json:"suggest_prune,omitempty"`
+}
@@ -0,0 +1,123 @@
+package restic
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"bytetrade.io/web3os/uploader-sdk/pkg/util/logger"
+)
+
+// the new password is handed to restic on stdin, so it is never written to
+// disk or shown in the process list
+const newPasswordFromStdin = "/dev/stdin"
+
+var keyAddedRegexp = regexp.MustCompile(`saved new key (?:with ID|as) ([0-9a-f]+)`)
+
+// Key is a key of the repository, every key holds its own password.
+type Key struct {
+	Current  bool   `json:"current"`
+	Id       string `json:"id"`
+	UserName string `json:"userName"`
+	HostName string `json:"hostName"`
+	Created  string `json:"created"`
+}
+
+// Is reports whether id is the id of k. restic key list prints short ids and
+// restic key add the full one, so either may be a prefix of the other.
+func (k *Key) Is(id string) bool {
+	if k.Id == "" || id == "" {
+		return false
+	}
+	return strings.HasPrefix(id, k.Id) || strings.HasPrefix(k.Id, id)
+}
+
+func (r *resticManager) ListKeys() ([]*Key, error) {
+	var keys []*Key
+	err := r.run([]string{
+		"key",
+		"list",
+		PARAM_JSON_OUTPUT,
+		PARAM_INSECURE_TLS,
+	}, func(res []byte) error {
+		var msg = string(res)
+		if strings.Contains(msg, "Fatal: ") {
+			logger.Debugf("[restic] key list %s error message: %s", r.name, msg)
+			return classify(msg)
+		}
+		return json.Unmarshal(res, &keys)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return keys, nil
+}
+
+// AddKey adds a key with password to the repository and returns its id.
+func (r *resticManager) AddKey(password string) (string, error) {
+	if password == "" {
+		return "", errors.New("new repository password is empty")
+	}
+	var id string
+	err := r.runStreams([]string{
+		"key",
+		"add",
+		PARAM_INSECURE_TLS,
+		"--new-password-file",
+		newPasswordFromStdin,
+	}, strings.NewReader(password), func(res []byte) error {
+		var msg = string(res)
+		logger.Debugf("[restic] key add %s message: %s", r.name, msg)
+		if strings.Contains(msg, "Fatal: ") {
+			return classify(msg)
+		}
+		if m := keyAddedRegexp.FindStringSubmatch(msg); m != nil {
+			id = m[1]
+		}
+		return nil
+	}, r.keyOutput)
+	if err != nil {
+		return "", err
+	}
+	if id == "" {
+		return "", fmt.Errorf("restic key add of %s printed no key id", r.name)
+	}
+
+	return id, nil
+}
+
+// RemoveKey removes the key id, restic refuses to remove the key in use.
+func (r *resticManager) RemoveKey(id string) error {
+	return r.run([]string{
+		"key",
+		"remove",
+		PARAM_INSECURE_TLS,
+		id,
+	}, r.keyOutput)
+}
+
+// ChangePassword replaces the password of the key in use.
+func (r *resticManager) ChangePassword(password string) error {
+	if password == "" {
+		return errors.New("new repository password is empty")
+	}
+	return r.runStreams([]string{
+		"key",
+		"passwd",
+		PARAM_INSECURE_TLS,
+		"--new-password-file",
+		newPasswordFromStdin,
+	}, strings.NewReader(password), r.keyOutput, r.keyOutput)
+}
+
+func (r *resticManager) keyOutput(res []byte) error {
+	var msg = string(res)
+	logger.Debugf("[restic] key %s message: %s", r.name, msg)
+	if strings.Contains(msg, "Fatal: ") {
+		return classify(msg)
+	}
+	return nil
+}
@@ -0,0 +1,33 @@
+package restic
+
+import "testing"
+
+func TestAddedKeyIsListed(t *testing.T) {
+	var fullId = "3e6d9c2f7b4a1e0d5c8b9a6f3e2d1c0b9a8f7e6d5c4b3a29180716253443526f"
+
+	id, err := fakeRestic(t, "repository 4bb8d4cb opened (version 2, compression level auto)\nsaved new key with ID "+fullId+"\n", "", 0).AddKey("new-password")
+	if err != nil {
+		t.Fatalf("AddKey() error = %v", err)
+	}
+	if id != fullId {
+		t.Fatalf("AddKey() = %q, want %q", id, fullId)
+	}
+
+	var list = `[{"current":true,"id":"3e6d9c2f","userName":"root","hostName":"olares","created":"2024-09-01 10:00:00"},{"current":false,"id":"a1b2c3d4","userName":"root","hostName":"olares","created":"2024-01-01 10:00:00"}]` + "\n"
+	keys, err := fakeRestic(t, list, "", 0).ListKeys()
+	if err != nil {
+		t.Fatalf("ListKeys() error = %v", err)
+	}
+	if len(keys) != 2 {
+		t.Fatalf("ListKeys() returned %d keys, want 2", len(keys))
+	}
+	if !keys[0].Is(id) || !keys[0].Current {
+		t.Errorf("key %s is not the current key %s", keys[0].Id, id)
+	}
+	if keys[1].Is(id) {
+		t.Errorf("key %s is %s", keys[1].Id, id)
+	}
+	if (&Key{}).Is(id) || keys[0].Is("") {
+		t.Errorf("empty ids match")
+	}
+}
@@ -0,0 +1,172 @@
+package restic
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"bytetrade.io/web3os/uploader-sdk/pkg/util/logger"
+)
+
+// Node is a file, directory or link stored in a snapshot.
+type Node struct {
+	Name        string      `json:"name"`
+	Type        string      `json:"type"` // "file", "dir" or "symlink"
+	Path        string      `json:"path"`
+	Size        uint64      `json:"size,omitempty"`
+	Mode        os.FileMode `json:"mode,omitempty"`
+	Permissions string      `json:"permissions,omitempty"`
+	ModTime     time.Time   `json:"mtime"`
+	Uid         uint32      `json:"uid"`
+	Gid         uint32      `json:"gid"`
+	User        string      `json:"user,omitempty"`
+	Group       string      `json:"group,omitempty"`
+	LinkTarget  string      `json:"link_target,omitempty"`
+}
+
+func (n *Node) IsDir() bool {
+	return n.Type == "dir"
+}
+
+// lsOutput is a line of restic ls --json, the first line describes the
+// snapshot and the others its nodes.
+type lsOutput struct {
+	Node
+	StructType  string `json:"struct_type"`
+	MessageType string `json:"message_type"`
+}
+
+// FindFilter narrows the snapshots and files restic find searches, only the
+// snapshots tagged with the name of the repository are searched.
+type FindFilter struct {
+	// SnapshotIds limits the search to these snapshots.
+	SnapshotIds []string
+	Hosts       []string
+	Paths       []string
+	// Oldest and Newest limit the matches by modification time.
+	Oldest     time.Time
+	Newest     time.Time
+	IgnoreCase bool
+}
+
+func (f *FindFilter) args(name string) []string {
+	var args = []string{"--tag", nameTag(name)}
+	if f == nil {
+		return args
+	}
+
+	for _, id := range f.SnapshotIds {
+		args = append(args, "--snapshot", id)
+	}
+	for _, host := range f.Hosts {
+		args = append(args, "--host", host)
+	}
+	for _, p := range f.Paths {
+		args = append(args, "--path", p)
+	}
+	if !f.Oldest.IsZero() {
+		args = append(args, "--oldest", f.Oldest.Local().Format(timeLayout))
+	}
+	if !f.Newest.IsZero() {
+		args = append(args, "--newest", f.Newest.Local().Format(timeLayout))
+	}
+	if f.IgnoreCase {
+		args = append(args, "--ignore-case")
+	}
+
+	return args
+}
+
+// FindMatch lists the nodes of a snapshot that match a find pattern.
+type FindMatch struct {
+	SnapshotId string  `json:"snapshot"`
+	Hits       int     `json:"hits"`
+	Matches    []*Node `json:"matches"`
+}
+
+// ListFiles returns the entries of the directory dir in the snapshot, or of
+// the root of the snapshot when dir is empty.
+func (r *resticManager) ListFiles(snapshotId string, dir string) ([]*Node, error) {
+	var args = []string{
+		"ls",
+		PARAM_JSON_OUTPUT,
+		PARAM_INSECURE_TLS,
+		snapshotId,
+	}
+	// without a directory restic lists the whole snapshot recursively
+	dir = path.Clean("/" + dir)
+	args = append(args, dir)
+
+	var nodes []*Node
+	err := r.run(args, func(res []byte) error {
+		var out lsOutput
+		if err := json.Unmarshal(res, &out); err != nil {
+			var msg = string(res)
+			logger.Debugf("[restic] ls %s message: %s", r.name, msg)
+			if strings.Contains(msg, "Fatal: ") {
+				return classify(msg)
+			}
+			return nil
+		}
+		if out.StructType != "node" || out.Path == dir {
+			return nil
+		}
+		var node = out.Node
+		nodes = append(nodes, &node)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return nodes, nil
+}
+
+// Find searches the snapshots for the files matching pattern, e.g. "*.jpg"
+// or "/home/*/notes.txt".
+func (r *resticManager) Find(pattern string, filter *FindFilter) ([]*FindMatch, error) {
+	var args = []string{
+		"find",
+		PARAM_JSON_OUTPUT,
+		PARAM_INSECURE_TLS,
+	}
+	args = append(args, filter.args(r.name)...)
+	args = append(args, "--", pattern)
+
+	// restic prints the matches as a single JSON array once it is done
+	var out bytes.Buffer
+	err := r.runStreams(args, nil, func(res []byte) error {
+		out.Write(res)
+		return nil
+	}, func(res []byte) error {
+		var msg = string(res)
+		logger.Debugf("[restic] find %s message: %s", r.name, msg)
+		if strings.Contains(msg, "Fatal: ") {
+			return classify(msg)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var matches []*FindMatch
+	if out.Len() == 0 {
+		return matches, nil
+	}
+	if err := json.Unmarshal(out.Bytes(), &matches); err != nil {
+		return nil, err
+	}
+	for _, m := range matches {
+		for _, node := range m.Matches {
+			if node.Name == "" {
+				node.Name = path.Base(node.Path)
+			}
+		}
+	}
+
+	return matches, nil
+}
@@ -0,0 +1,107 @@
+package restic
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+const lsSnapshotLine = `{"time":"2024-09-01T10:00:00.123456789+08:00","tree":"e4fd0e5a","paths":["/data"],"hostname":"olares","username":"root","id":"4bb8d4cb0a1f","short_id":"4bb8d4cb","struct_type":"snapshot","message_type":"snapshot"}`
+
+func TestListFiles(t *testing.T) {
+	var mtime = time.Date(2024, 9, 1, 9, 0, 0, 0, time.FixedZone("", 8*3600))
+	tests := []struct {
+		name     string
+		dir      string
+		stdout   string
+		wantArg  string
+		wantPath []string
+	}{
+		{
+			name: "root",
+			stdout: lsSnapshotLine + "\n" +
+				`{"name":"data","type":"dir","path":"/data","uid":0,"gid":0,"mode":2147484141,"permissions":"drwxr-xr-x","mtime":"2024-09-01T09:00:00+08:00","atime":"2024-09-01T09:00:00+08:00","ctime":"2024-09-01T09:00:00+08:00","struct_type":"node","message_type":"node"}` + "\n",
+			wantArg:  "/",
+			wantPath: []string{"/data"},
+		},
+		{
+			name: "directory",
+			dir:  "data/",
+			stdout: lsSnapshotLine + "\n" +
+				`{"name":"data","type":"dir","path":"/data","uid":0,"gid":0,"mode":2147484141,"permissions":"drwxr-xr-x","mtime":"2024-09-01T09:00:00+08:00","struct_type":"node","message_type":"node"}` + "\n" +
+				`{"name":"notes.txt","type":"file","path":"/data/notes.txt","uid":1000,"gid":1000,"size":12,"mode":420,"permissions":"-rw-r--r--","mtime":"2024-09-01T09:00:00+08:00","struct_type":"node","message_type":"node"}` + "\n" +
+				`{"name":"photos","type":"dir","path":"/data/photos","uid":1000,"gid":1000,"mode":2147484141,"permissions":"drwxr-xr-x","mtime":"2024-09-01T09:00:00+08:00","struct_type":"node","message_type":"node"}` + "\n",
+			wantArg:  "/data",
+			wantPath: []string{"/data/notes.txt", "/data/photos"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var r = fakeRestic(t, tt.stdout, "", 0)
+			nodes, err := r.ListFiles("4bb8d4cb", tt.dir)
+			if err != nil {
+				t.Fatalf("ListFiles() error = %v", err)
+			}
+
+			var args = fakeArgs(t, r)
+			if got := args[len(args)-1]; got != tt.wantArg {
+				t.Errorf("ListFiles() listed %q, want %q", got, tt.wantArg)
+			}
+			var paths []string
+			for _, node := range nodes {
+				paths = append(paths, node.Path)
+				if !node.ModTime.Equal(mtime) {
+					t.Errorf("node %s ModTime = %s, want %s", node.Path, node.ModTime, mtime)
+				}
+			}
+			if !reflect.DeepEqual(paths, tt.wantPath) {
+				t.Errorf("ListFiles() paths = %q, want %q", paths, tt.wantPath)
+			}
+		})
+	}
+}
+
+func TestFind(t *testing.T) {
+	var stdout = `[{"matches":[{"path":"/data/notes.txt","permissions":"-rw-r--r--","type":"file","mode":420,"mtime":"2024-09-01T09:00:00+08:00","atime":"2024-09-01T09:00:00+08:00","ctime":"2024-09-01T09:00:00+08:00","uid":1000,"gid":1000,"user":"alice","group":"alice","device_id":2049,"size":12,"links":1}],"hits":1,"snapshot":"4bb8d4cb0a1f"},{"matches":[{"path":"/data/old/notes.txt","permissions":"-rw-r--r--","type":"file","mode":420,"mtime":"2024-08-01T09:00:00+08:00","atime":"2024-08-01T09:00:00+08:00","ctime":"2024-08-01T09:00:00+08:00","uid":1000,"gid":1000,"user":"alice","group":"alice","device_id":2049,"size":7,"links":1}],"hits":1,"snapshot":"9e0a6f31c2d4"}]
+`
+	var r = fakeRestic(t, stdout, "", 0)
+	matches, err := r.Find("notes.txt", &FindFilter{Hosts: []string{"olares"}})
+	if err != nil {
+		t.Fatalf("Find() error = %v", err)
+	}
+
+	var wantArgs = []string{"find", PARAM_JSON_OUTPUT, PARAM_INSECURE_TLS, "--tag", nameTag("backup"), "--host", "olares", "--", "notes.txt"}
+	if args := fakeArgs(t, r); !reflect.DeepEqual(args, wantArgs) {
+		t.Errorf("Find() args = %q, want %q", args, wantArgs)
+	}
+
+	if len(matches) != 2 {
+		t.Fatalf("Find() returned %d snapshots, want 2", len(matches))
+	}
+	var got = map[string]string{}
+	for _, m := range matches {
+		if m.Hits != len(m.Matches) {
+			t.Errorf("snapshot %s Hits = %d, want %d", m.SnapshotId, m.Hits, len(m.Matches))
+		}
+		for _, node := range m.Matches {
+			got[m.SnapshotId] = node.Path
+			if node.Name != "notes.txt" || node.User != "alice" {
+				t.Errorf("node %s Name = %q, User = %q, want notes.txt and alice", node.Path, node.Name, node.User)
+			}
+		}
+	}
+	var want = map[string]string{"4bb8d4cb0a1f": "/data/notes.txt", "9e0a6f31c2d4": "/data/old/notes.txt"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Find() = %v, want %v", got, want)
+	}
+}
+
+func TestFindNoMatches(t *testing.T) {
+	matches, err := fakeRestic(t, "[]\n", "", 0).Find("*.jpg", nil)
+	if err != nil {
+		t.Fatalf("Find() error = %v", err)
+	}
+	if len(matches) != 0 {
+		t.Errorf("Find() = %v, want no matches", matches)
+	}
+}
@@ -0,0 +1,44 @@
+package restic
+
+type Phase string
+
+const (
+	PhaseTokenFetch Phase = "token_fetch"
+	PhaseInit       Phase = "init"
+	PhaseRepair     Phase = "repair"
+	PhaseBackup     Phase = "backup"
+	PhaseRestore    Phase = "restore"
+	PhaseForget     Phase = "forget"
+	PhasePrune      Phase = "prune"
+	PhaseCheck      Phase = "check"
+	PhaseCopy       Phase = "copy"
+)
+
+// ProgressEvent is reported to Option.Progress whenever a phase starts and
+// for every status message restic prints during backup and restore.
+type ProgressEvent struct {
+	Phase            Phase    `json:"phase"`
+	PercentDone      float64  `json:"percent_done"`
+	TotalFiles       uint64   `json:"total_files"`
+	FilesDone        uint64   `json:"files_done"`
+	TotalBytes       uint64   `json:"total_bytes"`
+	BytesDone        uint64   `json:"bytes_done"`
+	SecondsElapsed   uint64   `json:"seconds_elapsed"`
+	SecondsRemaining uint64   `json:"seconds_remaining"`
+	CurrentFiles     []string `json:"current_files,omitempty"`
+}
+
+// ProgressFunc is called from the goroutine reading restic output, it should
+// return quickly and must not block.
+type ProgressFunc func(event *ProgressEvent)
+
+func (f ProgressFunc) Report(event *ProgressEvent) {
+	if f == nil {
+		return
+	}
+	f(event)
+}
+
+func (f ProgressFunc) Phase(phase Phase) {
+	f.Report(&ProgressEvent{Phase: phase})
+}
@@ -3,8 +3,11 @@ package restic
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"math"
+	"path"
 	"strconv"
 	"strings"
 	"time"
@@ -35,6 +38,9 @@ const (
 	PARAM_INSECURE_TLS = "--insecure-tls"
 )
 
+// timeLayout is the format of the times passed to restic, in local time.
+const timeLayout = "2006-01-02 15:04:05"
+
 func (e RESTIC_ERROR_MESSAGE) Error() string {
 	return string(e)
 }
@@ -56,13 +62,32 @@ const (
 
 type Restic interface {
 	Init() (*InitSummaryOutput, error)
-	Backup(name string, folder string, filePathPrefix string) (*SummaryOutput, error)
+	Backup(name string, folder string, opts *BackupOptions, filePathPrefix string) (*SummaryOutput, error)
+	BackupStream(name string, filename string, rd io.Reader, opts *BackupOptions) (*SummaryOutput, error)
+	BackupCommand(name string, filename string, command []string, opts *BackupOptions) (*SummaryOutput, error)
 	Repair() error
 	Unlock() (string, error)
-	Restore(snapshotId string, uploadPath string, target string) (*RestoreSummaryOutput, error)
+	Restore(snapshotId string, uploadPath string, target string, opts *RestoreOptions) (*RestoreSummaryOutput, error)
 	NewContext()
 	RefreshEnv(envs map[string]string)
 	GetSnapshot(snapshotId string) (*Snapshot, error)
+	ListSnapshots(filter *SnapshotFilter) ([]*Snapshot, error)
+	Tag(snapshotIds []string, tags ...string) error
+	Untag(snapshotIds []string, tags ...string) error
+	ListFiles(snapshotId string, dir string) ([]*Node, error)
+	Find(pattern string, filter *FindFilter) ([]*FindMatch, error)
+	Diff(snapA string, snapB string) (*DiffReport, error)
+	Stats(mode StatsMode, snapshotIds ...string) (*Stats, error)
+	Copy(snapshotIds []string) (*CopyReport, error)
+	ListKeys() ([]*Key, error)
+	AddKey(password string) (string, error)
+	RemoveKey(id string) error
+	ChangePassword(password string) error
+	Dump(ctx context.Context, snapshotId string, path string) (io.ReadCloser, error)
+	DumpArchive(ctx context.Context, snapshotId string, path string, archive ArchiveFormat) (io.ReadCloser, error)
+	Forget(policy *RetentionPolicy) (*ForgetReport, error)
+	Prune() error
+	Check(opts *CheckOptions) (*CheckReport, error)
 	Cancel()
 }
 
@@ -79,8 +104,24 @@ type resticManager struct {
 type Option struct {
 	LimitDownloadRate string
 	LimitUploadRate   string
+	Progress          ProgressFunc
+	ErrorPolicy       ErrorPolicy
 }
 
+// ErrorPolicy decides how a backup that could not read some files ends.
+type ErrorPolicy string
+
+const (
+	// ErrorPolicyWarn logs every file error and returns the summary marked
+	// as incomplete, it is the default.
+	ErrorPolicyWarn ErrorPolicy = "warn"
+	// ErrorPolicyFail returns the summary along with ErrIncompleteSnapshot.
+	ErrorPolicyFail ErrorPolicy = "fail"
+	// ErrorPolicyIgnore returns the summary marked as incomplete without
+	// logging the file errors.
+	ErrorPolicyIgnore ErrorPolicy = "ignore"
+)
+
 func (o *Option) uploadRate() string {
 	var defaultUploadRate = "--limit-upload=0"
 	if o.LimitUploadRate == "" {
@@ -139,176 +180,228 @@ func (r *resticManager) RefreshEnv(envs map[string]string) {
 }
 
 func (r *resticManager) Init() (*InitSummaryOutput, error) {
-	opts := cmd.CommandOptions{
-		Path: r.bin,
-		Args: []string{
-			"init",
-			PARAM_JSON_OUTPUT,
-			PARAM_INSECURE_TLS,
-		},
-		Envs: r.envs,
-	}
-	c := cmd.NewCommand(r.ctx, opts)
-	var errorMsg RESTIC_ERROR_MESSAGE
 	var summary *InitSummaryOutput
-
-	go func() {
-		for {
-			select {
-			case res, ok := <-c.Ch:
-				if !ok {
-					return
-				}
-				if res == nil || len(res) == 0 {
-					continue
-				}
-				var msg = string(res)
-				logger.Debugf("[restic] init %s message: %s", r.name, msg)
-				if strings.Contains(msg, "Fatal: ") {
-					switch {
-					case strings.Contains(msg, ERROR_MESSAGE_ALREADY_INITIALIZED.Error()):
-						errorMsg = ERROR_MESSAGE_ALREADY_INITIALIZED
-						c.Cancel()
-						return
-					case
-						strings.Contains(msg, ERROR_MESSAGE_UNABLE_TO_OPEN_REPOSITORY.Error()),
-						strings.Contains(msg, ERROR_MESSAGE_BAD_REQUEST.Error()):
-						errorMsg = ERROR_MESSAGE_TOKEN_EXPIRED
-						c.Cancel()
-						return
-					default:
-						errorMsg = RESTIC_ERROR_MESSAGE(msg)
-						c.Cancel()
-						return
-					}
-				}
-				if err := json.Unmarshal(res, &summary); err != nil {
-					errorMsg = RESTIC_ERROR_MESSAGE(err.Error())
-					c.Cancel()
-					return
-				}
-			case <-r.ctx.Done():
-				return
+	var args = []string{
+		"init",
+		PARAM_JSON_OUTPUT,
+		PARAM_INSECURE_TLS,
+	}
+	if r.envs["RESTIC_FROM_REPOSITORY"] != "" {
+		// a copy destination shares the chunker of its source, otherwise
+		// the copied data is not deduplicated
+		args = append(args, "--copy-chunker-params")
+	}
+	err := r.run(args, func(res []byte) error {
+		var msg = string(res)
+		logger.Debugf("[restic] init %s message: %s", r.name, msg)
+		if strings.Contains(msg, "Fatal: ") {
+			switch {
+			case strings.Contains(msg, ERROR_MESSAGE_ALREADY_INITIALIZED.Error()):
+				return newError(ErrRepoAlreadyInitialized, msg)
+			case
+				strings.Contains(msg, ERROR_MESSAGE_UNABLE_TO_OPEN_REPOSITORY.Error()),
+				strings.Contains(msg, ERROR_MESSAGE_BAD_REQUEST.Error()):
+				return newError(ErrTokenExpired, msg)
+			default:
+				return classify(msg)
 			}
 		}
-	}()
+		return json.Unmarshal(res, &summary)
+	})
+	if err != nil {
+		return nil, err
+	}
 
-	_, err := c.Run()
+	return summary, nil
+}
+
+func (r *resticManager) Backup(name string, folder string, opts *BackupOptions, filePathPrefix string) (*SummaryOutput, error) {
+	var args = []string{
+		"backup",
+		r.opt.uploadRate(),
+		PARAM_JSON_OUTPUT,
+		PARAM_INSECURE_TLS,
+	}
+	args = append(args, r.withTag(name)...)
+	snapshotArgs, err := opts.snapshotArgs()
 	if err != nil {
 		return nil, err
 	}
-	if errorMsg != "" {
-		return nil, fmt.Errorf(errorMsg.Error())
+	args = append(args, snapshotArgs...)
+	args = append(args, opts.args()...)
+	if folder != "" {
+		args = append(args, folder)
 	}
+	args = append(args, opts.paths()...)
 
-	return summary, nil
+	return r.backup(args, nil, filePathPrefix)
 }
 
-func (r *resticManager) Backup(name string, folder string, filePathPrefix string) (*SummaryOutput, error) {
-	var backupCtx, cancel = context.WithCancel(r.ctx)
-	defer cancel()
-	opts := cmd.CommandOptions{
-		Path: r.bin,
-		Args: []string{
-			"backup",
-			folder,
-			r.opt.uploadRate(),
-			PARAM_JSON_OUTPUT,
-			PARAM_INSECURE_TLS,
-		},
-		Envs: r.envs,
+// BackupStream saves the content of rd as the file filename of a new
+// snapshot, rd is read once and a failed backup can not be run again. Only
+// the snapshot metadata of opts applies.
+func (r *resticManager) BackupStream(name string, filename string, rd io.Reader, opts *BackupOptions) (*SummaryOutput, error) {
+	var args = []string{
+		"backup",
+		r.opt.uploadRate(),
+		PARAM_JSON_OUTPUT,
+		PARAM_INSECURE_TLS,
+		"--stdin",
+	}
+	args = append(args, stdinFilename(filename)...)
+	args = append(args, r.withTag(name)...)
+	snapshotArgs, err := opts.snapshotArgs()
+	if err != nil {
+		return nil, err
 	}
+	args = append(args, snapshotArgs...)
 
-	opts.Args = append(opts.Args, r.withTag(name)...)
+	return r.backup(args, rd, "")
+}
 
-	c := cmd.NewCommand(backupCtx, opts)
+// BackupCommand saves the output of command as the file filename of a new
+// snapshot, restic starts the command and fails the backup when it exits
+// with a non-zero code. It requires restic 0.17 or later. Only the snapshot
+// metadata of opts applies.
+func (r *resticManager) BackupCommand(name string, filename string, command []string, opts *BackupOptions) (*SummaryOutput, error) {
+	if len(command) == 0 {
+		return nil, errors.New("backup command is empty")
+	}
 
+	var args = []string{
+		"backup",
+		r.opt.uploadRate(),
+		PARAM_JSON_OUTPUT,
+		PARAM_INSECURE_TLS,
+	}
+	args = append(args, stdinFilename(filename)...)
+	args = append(args, r.withTag(name)...)
+	snapshotArgs, err := opts.snapshotArgs()
+	if err != nil {
+		return nil, err
+	}
+	args = append(args, snapshotArgs...)
+	args = append(args, "--stdin-from-command", "--")
+	args = append(args, command...)
+
+	return r.backup(args, nil, "")
+}
+
+// stdinFilename names the file of a stdin backup, restic names it "stdin"
+// when filename is empty.
+func stdinFilename(filename string) []string {
+	if filename == "" {
+		return nil
+	}
+	return []string{"--stdin-filename", filename}
+}
+
+// backup runs restic backup with args and collects its summary.
+func (r *resticManager) backup(args []string, stdin io.Reader, filePathPrefix string) (*SummaryOutput, error) {
 	var prevPercent float64
 	var finished bool
 	var summary *SummaryOutput
-	var errorMsg RESTIC_ERROR_MESSAGE
-
-	go func() {
-		for {
-			select {
-			case res, ok := <-c.Ch:
-				if !ok {
-					return
-				}
-				if res == nil || len(res) == 0 {
-					continue
-				}
-
-				status := messagePool.Get()
-				if err := json.Unmarshal(res, status); err != nil {
-					var msg = string(res)
-					logger.Debugf("[restic] backup %s error message: %s", r.name, msg)
-					messagePool.Put(status)
-					switch {
-					case strings.Contains(msg, ERROR_MESSAGE_TOKEN_EXPIRED.Error()):
-						errorMsg = ERROR_MESSAGE_TOKEN_EXPIRED
-						c.Cancel()
-						return
-					case strings.Contains(msg, ERROR_MESSAGE_UNABLE_TO_OPEN_CONFIG_FILE.Error()):
-						errorMsg = ERROR_MESSAGE_UNABLE_TO_OPEN_CONFIG_FILE
-						c.Cancel()
-						return
-					default:
-						errorMsg = RESTIC_ERROR_MESSAGE(msg)
-						c.Cancel()
-						return
-					}
+	var fileErrors []*ErrorUpdate
+
+	var handle = func(res []byte) error {
+		status := messagePool.Get()
+		defer messagePool.Put(status)
+
+		if err := json.Unmarshal(res, status); err != nil {
+			var msg = string(res)
+			logger.Debugf("[restic] backup %s error message: %s", r.name, msg)
+			switch {
+			case strings.Contains(msg, "Fatal: "):
+				return classify(msg)
+			case strings.HasPrefix(msg, "error: "):
+				// restic before 0.17 prints file errors as plain text
+				fileErrors = append(fileErrors, r.fileError(&ErrorUpdate{
+					MessageType: "error",
+					Error:       ErrorObject{Message: strings.TrimPrefix(msg, "error: ")},
+				}))
+				return nil
+			}
+			// restic retries network errors on its own, every other known
+			// failure would only repeat until restic gives up
+			if err := classify(msg); err.Kind != nil && err.Kind != ErrNetwork {
+				return err
+			}
+			return nil
+		}
+		switch status.MessageType {
+		case "error":
+			var fileError *ErrorUpdate
+			if err := json.Unmarshal(res, &fileError); err != nil {
+				return err
+			}
+			fileErrors = append(fileErrors, r.fileError(fileError))
+		case "status":
+			r.opt.Progress.Report(&ProgressEvent{
+				Phase:            PhaseBackup,
+				PercentDone:      status.PercentDone,
+				TotalFiles:       status.TotalFiles,
+				FilesDone:        status.FilesDone,
+				TotalBytes:       status.TotalBytes,
+				BytesDone:        status.BytesDone,
+				SecondsElapsed:   status.SecondsElapsed,
+				SecondsRemaining: status.SecondsRemaining,
+				CurrentFiles:     r.fileNameTidy(status.CurrentFiles, filePathPrefix),
+			})
+			switch {
+			case math.Abs(status.PercentDone-0.0) < tolerance:
+				logger.Infof(PRINT_START_MESSAGE, status.TotalFiles, util.FormatBytes(status.TotalBytes))
+			case math.Abs(status.PercentDone-1.0) < tolerance:
+				if !finished {
+					logger.Infof(PRINT_FINISH_MESSAGE, status.TotalFiles, util.FormatBytes(status.TotalBytes))
+					finished = true
 				}
-				switch status.MessageType {
-				case "status":
-					switch {
-					case math.Abs(status.PercentDone-0.0) < tolerance:
-						logger.Infof(PRINT_START_MESSAGE, status.TotalFiles, util.FormatBytes(status.TotalBytes))
-					case math.Abs(status.PercentDone-1.0) < tolerance:
-						if !finished {
-							logger.Infof(PRINT_FINISH_MESSAGE, status.TotalFiles, util.FormatBytes(status.TotalBytes))
-							finished = true
-						}
-					default:
-						if prevPercent != status.PercentDone {
-							logger.Infof(PRINT_PROGRESS_MESSAGE,
-								status.GetPercentDone(),
-								status.FilesDone,
-								status.TotalFiles,
-								util.FormatBytes(status.BytesDone),
-								util.FormatBytes(status.TotalBytes),
-								r.fileNameTidy(status.CurrentFiles, filePathPrefix))
-						}
-						prevPercent = status.PercentDone
-					}
-				case "summary":
-					if err := json.Unmarshal(res, &summary); err != nil {
-						logger.Debugf("[restic] backup %s error summary unmarshal message: %s", r.name, string(res))
-						messagePool.Put(status)
-						errorMsg = RESTIC_ERROR_MESSAGE(err.Error())
-						c.Cancel()
-						return
-					}
-					messagePool.Put(status)
-					return
+			default:
+				if prevPercent != status.PercentDone {
+					logger.Infof(PRINT_PROGRESS_MESSAGE,
+						status.GetPercentDone(),
+						status.FilesDone,
+						status.TotalFiles,
+						util.FormatBytes(status.BytesDone),
+						util.FormatBytes(status.TotalBytes),
+						r.fileNameTidy(status.CurrentFiles, filePathPrefix))
 				}
-				messagePool.Put(status)
-			case <-r.ctx.Done():
-				return
+				prevPercent = status.PercentDone
+			}
+		case "summary":
+			if err := json.Unmarshal(res, &summary); err != nil {
+				logger.Debugf("[restic] backup %s error summary unmarshal message: %s", r.name, string(res))
+				return err
 			}
 		}
-	}()
-
-	_, err := c.Run()
+		return nil
+	}
+	err := r.runStreams(args, stdin, handle, handle)
+	var resticErr *Error
+	if errors.As(err, &resticErr) && resticErr.ExitCode == exitCodeIncomplete && summary != nil {
+		// the snapshot is saved, the unreadable files were reported above
+		err = nil
+	}
 	if err != nil {
 		return nil, err
 	}
-	if errorMsg != "" {
-		return nil, fmt.Errorf(errorMsg.Error())
+
+	if summary != nil && len(fileErrors) > 0 {
+		summary.Incomplete = true
+		summary.FileErrors = fileErrors
+		if r.opt.ErrorPolicy == ErrorPolicyFail {
+			return summary, newError(ErrIncompleteSnapshot, fmt.Sprintf("snapshot %s misses %d unreadable files", summary.SnapshotID, len(fileErrors)))
+		}
 	}
 	return summary, nil
 }
 
+func (r *resticManager) fileError(e *ErrorUpdate) *ErrorUpdate {
+	if r.opt.ErrorPolicy != ErrorPolicyIgnore {
+		logger.Warnf("[restic] backup %s could not read %q during %s: %s", r.name, e.Item, e.During, e.Error.Message)
+	}
+	return e
+}
+
 func (r *resticManager) Repair() error {
 	backoff := wait.Backoff{
 		Duration: 2 * time.Second,
@@ -318,87 +411,37 @@ func (r *resticManager) Repair() error {
 	}
 
 	if err := retry.OnError(backoff, func(err error) bool {
-		return true
+		return errors.Is(err, ErrRepoLocked) || errors.Is(err, ErrNetwork)
 	}, func() error {
-		res, err := r.repairIndex()
-		if err != nil {
-			return err
-		}
-
-		if strings.Contains(res, ERROR_MESSAGE_LOCKED.Error()) {
+		err := r.repairIndex()
+		if errors.Is(err, ErrRepoLocked) {
 			r.Unlock()
-			return fmt.Errorf("retry")
 		}
-		return nil
+		return err
 	}); err != nil {
 		return err
 	}
 	return nil
 }
 
-func (r *resticManager) repairIndex() (string, error) {
-	opts := cmd.CommandOptions{
-		Path:  r.bin,
-		Args:  []string{"repair", "index", PARAM_INSECURE_TLS},
-		Envs:  r.envs,
-		Print: true,
-	}
-	c := cmd.NewCommand(r.ctx, opts)
-
-	sb := new(strings.Builder)
-	go func() {
-		for {
-			select {
-			case res, ok := <-c.Ch:
-				if !ok {
-					return
-				}
-				if res == nil || len(res) == 0 {
-					continue
-				}
-				logger.Debugf("[restic] repair %s message: %s", r.name, string(res))
-				sb.WriteString(string(res) + "\n")
-			case <-r.ctx.Done():
-				return
-			}
+func (r *resticManager) repairIndex() error {
+	return r.run([]string{"repair", "index", PARAM_INSECURE_TLS}, func(res []byte) error {
+		var msg = string(res)
+		logger.Debugf("[restic] repair %s message: %s", r.name, msg)
+		if strings.Contains(msg, ERROR_MESSAGE_LOCKED.Error()) || strings.Contains(msg, "Fatal: ") {
+			return classify(msg)
 		}
-	}()
-
-	_, err := c.Run()
-	if err != nil {
-		return "", err
-	}
-	return sb.String(), nil
+		return nil
+	})
 }
 
 func (r *resticManager) Unlock() (string, error) {
-	opts := cmd.CommandOptions{
-		Path: r.bin,
-		Args: []string{"unlock", "--remove-all", PARAM_INSECURE_TLS},
-		Envs: r.envs,
-	}
-	c := cmd.NewCommand(r.ctx, opts)
 	sb := new(strings.Builder)
-
-	go func() {
-		for {
-			select {
-			case res, ok := <-c.Ch:
-				if !ok {
-					return
-				}
-				if res == nil || len(res) == 0 {
-					continue
-				}
-				logger.Debugf("[restic] unlock %s message: %s", r.name, string(res))
-				sb.WriteString(string(res) + "\n")
-			case <-r.ctx.Done():
-				return
-			}
-		}
-	}()
-
-	_, err := c.Run()
+	err := r.run([]string{"unlock", "--remove-all", PARAM_INSECURE_TLS}, func(res []byte) error {
+		logger.Debugf("[restic] unlock %s message: %s", r.name, string(res))
+		sb.WriteString(string(res) + "\n")
+		return nil
+	})
 	if err != nil {
 		return "", err
 	}
@@ -406,191 +449,129 @@ func (r *resticManager) Unlock() (string, error) {
 }
 
 func (r *resticManager) GetSnapshot(snapshotId string) (*Snapshot, error) {
-	var restoreCtx, cancel = context.WithCancel(r.ctx)
-	defer cancel()
-	opts := cmd.CommandOptions{
-		Path: r.bin,
-		Args: []string{
-			"snapshots",
-			PARAM_JSON_OUTPUT,
-			PARAM_INSECURE_TLS,
-			snapshotId,
-		},
-		Envs: r.envs,
-	}
-
-	c := cmd.NewCommand(restoreCtx, opts)
-
 	var summary []*Snapshot
-	var errorMsg RESTIC_ERROR_MESSAGE
-
-	go func() {
-		for {
-			select {
-			case res, ok := <-c.Ch:
-				if !ok {
-					return
-				}
-				if res == nil || len(res) == 0 {
-					continue
-				}
-
-				var msg = string(res)
-				logger.Debugf("[restic] snapshots %s message: %s", r.name, msg)
-				if strings.Contains(msg, "Fatal: ") {
-					switch {
-					case strings.Contains(msg, ERROR_MESSAGE_SNAPSHOT_NOT_FOUND.Error()):
-						errorMsg = ERROR_MESSAGE_SNAPSHOT_NOT_FOUND
-						c.Cancel()
-						return
-					default:
-						errorMsg = RESTIC_ERROR_MESSAGE(msg)
-						c.Cancel()
-						return
-					}
-				}
-				if err := json.Unmarshal(res, &summary); err != nil {
-					errorMsg = RESTIC_ERROR_MESSAGE(err.Error())
-					c.Cancel()
-					return
-				}
-			case <-r.ctx.Done():
-				return
-			}
+	err := r.run([]string{
+		"snapshots",
+		PARAM_JSON_OUTPUT,
+		PARAM_INSECURE_TLS,
+		snapshotId,
+	}, func(res []byte) error {
+		var msg = string(res)
+		logger.Debugf("[restic] snapshots %s message: %s", r.name, msg)
+		if strings.Contains(msg, "Fatal: ") {
+			return classify(msg)
 		}
-	}()
-
-	_, err := c.Run()
+		return json.Unmarshal(res, &summary)
+	})
 	if err != nil {
 		return nil, err
 	}
-	if errorMsg != "" {
-		return nil, fmt.Errorf(errorMsg.Error())
-	}
 
-	if summary == nil || len(summary) == 0 {
-		return nil, fmt.Errorf("snapshot %s not found", snapshotId)
+	if len(summary) == 0 {
+		return nil, newError(ErrSnapshotNotFound, fmt.Sprintf("snapshot %s not found", snapshotId))
 	}
 
 	return summary[0], nil
 }
 
-func (r *resticManager) Restore(snapshotId string, uploadPath string, target string) (*RestoreSummaryOutput, error) {
-	var restoreCtx, cancel = context.WithCancel(r.ctx)
-	defer cancel()
-	opts := cmd.CommandOptions{
-		Path: r.bin,
-		Args: []string{
-			"restore",
-			r.opt.downloadRate(),
-			"-t",
-			target,
-			"-v=3",
-			PARAM_JSON_OUTPUT,
-			PARAM_INSECURE_TLS,
-			fmt.Sprintf("%s:%s", snapshotId, uploadPath),
-		},
-		Envs: r.envs,
-	}
-
-	c := cmd.NewCommand(restoreCtx, opts)
+func (r *resticManager) Restore(snapshotId string, uploadPath string, target string, opts *RestoreOptions) (*RestoreSummaryOutput, error) {
+	if err := opts.validate(); err != nil {
+		return nil, err
+	}
+
+	var args = []string{
+		"restore",
+		r.opt.downloadRate(),
+		"-t",
+		target,
+		"-v=3",
+		PARAM_JSON_OUTPUT,
+		PARAM_INSECURE_TLS,
+	}
+	args = append(args, opts.args()...)
+	var p = opts.snapshotPath(uploadPath)
+	if p != uploadPath {
+		isFile, err := r.isFile(snapshotId, p)
+		if err != nil {
+			return nil, err
+		}
+		if isFile {
+			args = append(args, "--include", "/"+path.Base(p))
+			p = path.Dir(p)
+		}
+	}
+	if p != "/" {
+		args = append(args, fmt.Sprintf("%s:%s", snapshotId, p))
+	} else {
+		args = append(args, snapshotId)
+	}
 
 	var prevPercent float64
 	var started bool
 	var finished bool
 	var summary *RestoreSummaryOutput
-	var errorMsg RESTIC_ERROR_MESSAGE
 
-	go func() {
-		for {
-			select {
-			case res, ok := <-c.Ch:
-				if !ok {
-					return
-				}
-				if res == nil || len(res) == 0 {
-					continue
-				}
+	err := r.run(args, func(res []byte) error {
+		status := restoreMessagePool.Get()
+		defer restoreMessagePool.Put(status)
 
-				status := restoreMessagePool.Get()
-				if err := json.Unmarshal(res, status); err != nil {
-					var msg = string(res)
-					logger.Debugf("[restic] restore %s error message: %s", r.name, msg)
-					restoreMessagePool.Put(status)
-
-					switch {
-					case strings.Contains(msg, ERROR_MESSAGE_TOKEN_EXPIRED.Error()):
-						errorMsg = ERROR_MESSAGE_TOKEN_EXPIRED
-						c.Cancel()
-						return
-					case strings.Contains(msg, ERROR_MESSAGE_UNABLE_TO_OPEN_CONFIG_FILE.Error()):
-						errorMsg = ERROR_MESSAGE_UNABLE_TO_OPEN_CONFIG_FILE
-						c.Cancel()
-						return
-					default:
-						errorMsg = RESTIC_ERROR_MESSAGE(msg)
-						c.Cancel()
-						return
-					}
+		if err := json.Unmarshal(res, status); err != nil {
+			var msg = string(res)
+			logger.Debugf("[restic] restore %s error message: %s", r.name, msg)
+			return classify(msg)
+		}
+		switch status.MessageType {
+		case "status":
+			r.opt.Progress.Report(&ProgressEvent{
+				Phase:            PhaseRestore,
+				PercentDone:      status.PercentDone,
+				TotalFiles:       status.TotalFiles,
+				FilesDone:        status.FilesRestored + status.FilesSkipped,
+				TotalBytes:       status.TotalBytes,
+				BytesDone:        status.BytesRestored + status.BytesSkipped,
+				SecondsElapsed:   status.SecondsElapsed,
+				SecondsRemaining: status.SecondsRemaining,
+			})
+			switch {
+			case math.Abs(status.PercentDone-0.0) < tolerance:
+				if !started {
+					logger.Infof(PRINT_RESTORE_START_MESSAGE, status.TotalFiles, util.FormatBytes(status.TotalBytes))
+					started = true
+				}
+			case math.Abs(status.PercentDone-1.0) < tolerance:
+				if !finished {
+					logger.Infof(PRINT_RESTORE_FINISH_MESSAGE, snapshotId, status.TotalFiles, status.FilesRestored, util.FormatBytes(status.TotalBytes), util.FormatBytes(status.BytesRestored))
+					finished = true
 				}
-				switch status.MessageType {
-				case "status":
-					switch {
-					case math.Abs(status.PercentDone-0.0) < tolerance:
-						if !started {
-							logger.Infof(PRINT_RESTORE_START_MESSAGE, status.TotalFiles, util.FormatBytes(status.TotalBytes))
-							started = true
-						}
-					case math.Abs(status.PercentDone-1.0) < tolerance:
-						if !finished {
-
-							logger.Infof(PRINT_RESTORE_FINISH_MESSAGE, snapshotId, status.TotalFiles, status.FilesRestored, util.FormatBytes(status.TotalBytes), util.FormatBytes(status.BytesRestored))
-							finished = true
-						}
-					default:
-						if prevPercent != status.PercentDone {
-							logger.Infof(PRINT_RESTORE_PROGRESS_MESSAGE,
-								status.GetPercentDone(),
-								status.FilesRestored,
-								status.TotalFiles,
-								util.FormatBytes(status.BytesRestored),
-								util.FormatBytes(status.TotalBytes),
-							)
-						}
-						prevPercent = status.PercentDone
-					}
-				case "verbose_status":
-					rvu := new(RestoreVerboseUpdate)
-					if err := json.Unmarshal(res, &rvu); err != nil {
-						errorMsg = RESTIC_ERROR_MESSAGE(err.Error())
-						c.Cancel()
-						return
-					}
-					logger.Infof(PRINT_RESTORE_ITEM, rvu.Item, util.FormatBytes(rvu.Size))
-				case "summary":
-					if err := json.Unmarshal(res, &summary); err != nil {
-						logger.Debugf("[restic] restore %s error summary unmarshal message: %s", r.name, string(res))
-						restoreMessagePool.Put(status)
-						errorMsg = RESTIC_ERROR_MESSAGE(err.Error())
-						c.Cancel()
-						return
-					}
-					restoreMessagePool.Put(status)
-					return
+			default:
+				if prevPercent != status.PercentDone {
+					logger.Infof(PRINT_RESTORE_PROGRESS_MESSAGE,
+						status.GetPercentDone(),
+						status.FilesRestored,
+						status.TotalFiles,
+						util.FormatBytes(status.BytesRestored),
+						util.FormatBytes(status.TotalBytes),
+					)
 				}
-				restoreMessagePool.Put(status)
-			case <-r.ctx.Done():
-				return
+				prevPercent = status.PercentDone
+			}
+		case "verbose_status":
+			rvu := new(RestoreVerboseUpdate)
+			if err := json.Unmarshal(res, &rvu); err != nil {
+				return err
+			}
+			logger.Infof(PRINT_RESTORE_ITEM, rvu.Item, util.FormatBytes(rvu.Size))
+		case "summary":
+			if err := json.Unmarshal(res, &summary); err != nil {
+				logger.Debugf("[restic] restore %s error summary unmarshal message: %s", r.name, string(res))
+				return err
 			}
 		}
-	}()
-	_, err := c.Run()
+		return nil
+	})
 	if err != nil {
 		return nil, err
 	}
-	if errorMsg != "" {
-		return nil, fmt.Errorf(errorMsg.Error())
-	}
 
 	return summary, nil
 }
@@ -609,5 +590,93 @@ func (r *resticManager) fileNameTidy(f []string, prefix string) []string {
 }
 
 func (r *resticManager) withTag(name string) []string {
-	return []string{"--tag", fmt.Sprintf("name=%s", name)}
+	return []string{"--tag", nameTag(name)}
+}
+
+func nameTag(name string) string {
+	return fmt.Sprintf("name=%s", name)
+}
+
+// run executes restic with args and hands every non-empty stdout and stderr
+// line to handle. The first error returned by handle stops the command and is
+// returned in preference to the command error. A non-zero exit is returned as
+// an *Error classified from the stderr tail.
+func (r *resticManager) run(args []string, handle func(res []byte) error) error {
+	return r.runStreams(args, nil, handle, handle)
+}
+
+// runStreams is run with separate handlers for the stdout and stderr lines,
+// they are never called concurrently.
+func (r *resticManager) runStreams(args []string, stdin io.Reader, handleStdout, handleStderr func(res []byte) error) error {
+	var runCtx, cancel = context.WithCancel(r.ctx)
+	defer cancel()
+	opts := cmd.CommandOptions{
+		Path:        r.bin,
+		Args:        args,
+		Envs:        r.envs,
+		Stdin:       stdin,
+		SplitStderr: true,
+	}
+	c := cmd.NewCommand(runCtx, opts)
+
+	var handleErr error
+	var done = make(chan struct{})
+	go func() {
+		defer close(done)
+		var stdout, stderr = c.Ch, c.ErrCh
+		for stdout != nil || stderr != nil {
+			var res []byte
+			var ok bool
+			var handle func(res []byte) error
+			select {
+			case res, ok = <-stdout:
+				if !ok {
+					stdout = nil
+					continue
+				}
+				handle = handleStdout
+			case res, ok = <-stderr:
+				if !ok {
+					stderr = nil
+					continue
+				}
+				handle = handleStderr
+			}
+			if handleErr != nil || len(res) == 0 {
+				continue
+			}
+			if err := handle(res); err != nil {
+				handleErr = err
+				c.Cancel()
+			}
+		}
+	}()
+
+	_, err := c.Run()
+	<-done
+	if handleErr != nil {
+		return handleErr
+	}
+
+	return fromExitError(err)
+}
+
+// fromExitError turns the *cmd.ExitError of a failed restic command into an
+// *Error classified from its stderr tail, other errors are returned as is.
+func fromExitError(err error) error {
+	var exitErr *cmd.ExitError
+	if !errors.As(err, &exitErr) {
+		return err
+	}
+
+	var msg = exitErr.Stderr
+	if msg == "" {
+		msg = exitErr.Error()
+	}
+	var e = classify(msg)
+	e.ExitCode = exitErr.ExitCode
+	if e.Kind == nil {
+		e.Kind = exitCodeKinds[e.ExitCode]
+	}
+	return e
 }
@@ -0,0 +1,60 @@
+package restic
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"bytetrade.io/web3os/uploader-sdk/pkg/util/logger"
+	"go.uber.org/zap"
+)
+
+func init() {
+	logger.SetLogger(zap.NewNop().Sugar())
+}
+
+// fakeRestic returns a resticManager whose restic prints stdout and stderr
+// and exits with code, for replaying recorded restic output. The arguments
+// restic was run with are returned by fakeArgs.
+func fakeRestic(t *testing.T, stdout, stderr string, code int) *resticManager {
+	t.Helper()
+
+	var dir = t.TempDir()
+	var files = map[string]string{"stdout": stdout, "stderr": stderr}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var script = fmt.Sprintf("#!/bin/sh\nprintf '%%s\\n' \"$@\" > %q\ncat %q\ncat %q >&2\nexit %d\n",
+		filepath.Join(dir, "args"), filepath.Join(dir, "stdout"), filepath.Join(dir, "stderr"), code)
+	var bin = filepath.Join(dir, "restic")
+	if err := os.WriteFile(bin, []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	return &resticManager{
+		ctx:    ctx,
+		cancel: cancel,
+		name:   "backup",
+		bin:    bin,
+		opt:    &Option{},
+	}
+}
+
+// fakeArgs returns the arguments of the last run of the restic of r.
+func fakeArgs(t *testing.T, r *resticManager) []string {
+	t.Helper()
+
+	data, err := os.ReadFile(filepath.Join(filepath.Dir(r.bin), "args"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+}
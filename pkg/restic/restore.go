@@ -0,0 +1,115 @@
+package restic
+
+import (
+	"fmt"
+	"path"
+	"strings"
+)
+
+// OverwritePolicy decides which existing files a restore replaces.
+type OverwritePolicy string
+
+const (
+	OverwriteAlways    OverwritePolicy = "always"
+	OverwriteIfChanged OverwritePolicy = "if-changed"
+	OverwriteIfNewer   OverwritePolicy = "if-newer"
+	OverwriteNever     OverwritePolicy = "never"
+)
+
+// RestoreOptions selects the files of a restore and how they are written.
+type RestoreOptions struct {
+	// Path picks the backed up folder to restore from a snapshot of several
+	// paths, see BackupOptions.Paths. Such a snapshot is restored from its
+	// root when Path is empty, every folder below the target at its full
+	// path.
+	Path string
+	// SubPath restores only this path inside the backed up folder, e.g.
+	// "etc/nginx" or "home/user/notes.txt". The content of a folder is
+	// restored into the target, a file is restored into it by its name.
+	SubPath string
+	// Includes are patterns of the files to restore, the other files are
+	// skipped.
+	Includes []string
+	// Excludes are patterns of the files to skip.
+	Excludes []string
+	// Overwrite defaults to the restic default, OverwriteAlways.
+	Overwrite OverwritePolicy
+	// Delete removes the files of the target that are not in the snapshot.
+	Delete bool
+	// Verify reads the restored files back and checks their content.
+	Verify bool
+	// Sparse restores sparse files as such.
+	Sparse bool
+}
+
+func (o *RestoreOptions) validate() error {
+	if o == nil {
+		return nil
+	}
+
+	switch o.Overwrite {
+	case "", OverwriteAlways, OverwriteIfChanged, OverwriteIfNewer, OverwriteNever:
+	default:
+		return fmt.Errorf("unknown overwrite policy %q", o.Overwrite)
+	}
+
+	for _, part := range strings.Split(o.SubPath, "/") {
+		if part == ".." {
+			return fmt.Errorf("sub path %q leaves the backed up folder", o.SubPath)
+		}
+	}
+
+	return nil
+}
+
+func (o *RestoreOptions) args() []string {
+	if o == nil {
+		return nil
+	}
+
+	var args []string
+	for _, i := range o.Includes {
+		args = append(args, "--include", i)
+	}
+	for _, e := range o.Excludes {
+		args = append(args, "--exclude", e)
+	}
+	if o.Overwrite != "" {
+		args = append(args, "--overwrite", string(o.Overwrite))
+	}
+	if o.Delete {
+		args = append(args, "--delete")
+	}
+	if o.Verify {
+		args = append(args, "--verify")
+	}
+	if o.Sparse {
+		args = append(args, "--sparse")
+	}
+
+	return args
+}
+
+// snapshotPath returns the path of the snapshot to restore below uploadPath.
+func (o *RestoreOptions) snapshotPath(uploadPath string) string {
+	if o == nil || o.SubPath == "" {
+		return uploadPath
+	}
+	return path.Join(uploadPath, path.Clean("/"+o.SubPath))
+}
+
+// isFile tells whether p of the snapshot is something else than a folder.
+// restic restores only folders as snapshot:path, a file is restored from its
+// folder with an include pattern instead.
+func (r *resticManager) isFile(snapshotId string, p string) (bool, error) {
+	nodes, err := r.ListFiles(snapshotId, path.Dir(p))
+	if err != nil {
+		return false, err
+	}
+	for _, node := range nodes {
+		if node.Path == p {
+			return !node.IsDir(), nil
+		}
+	}
+	return false, nil
+}
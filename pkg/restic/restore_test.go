@@ -0,0 +1,70 @@
+package restic
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestRestoreArgs(t *testing.T) {
+	// the fake restic prints the same output for restic ls, which looks up
+	// the sub path, and for restic restore
+	var stdout = `{"time":"2024-09-01T10:00:00Z","tree":"c3","paths":["/data"],"hostname":"olares","username":"root","id":"4bb8d4cb","short_id":"4bb8d4cb","struct_type":"snapshot","message_type":"snapshot"}
+{"name":"user","type":"dir","path":"/data/home/user","mtime":"2024-09-01T09:00:00Z","struct_type":"node","message_type":"node"}
+{"name":"notes.txt","type":"file","path":"/data/home/user/notes.txt","size":12,"mtime":"2024-09-01T09:00:00Z","struct_type":"node","message_type":"node"}
+{"name":"docs","type":"dir","path":"/data/home/user/docs","mtime":"2024-09-01T09:00:00Z","struct_type":"node","message_type":"node"}
+{"message_type":"summary","seconds_elapsed":1,"total_files":1,"files_restored":1,"total_bytes":12,"bytes_restored":12}
+`
+	var prefix = []string{"restore", "--limit-download=0", "-t", "/restore", "-v=3", PARAM_JSON_OUTPUT, PARAM_INSECURE_TLS}
+
+	tests := []struct {
+		name       string
+		uploadPath string
+		opts       *RestoreOptions
+		wantArgs   []string
+	}{
+		{
+			name:       "whole snapshot",
+			uploadPath: "/data",
+			wantArgs:   []string{"4bb8d4cb:/data"},
+		},
+		{
+			name:       "root of several paths",
+			uploadPath: "/",
+			wantArgs:   []string{"4bb8d4cb"},
+		},
+		{
+			name:       "flags",
+			uploadPath: "/data",
+			opts:       &RestoreOptions{Overwrite: OverwriteIfChanged, Delete: true, Verify: true, Sparse: true},
+			wantArgs:   []string{"--overwrite", "if-changed", "--delete", "--verify", "--sparse", "4bb8d4cb:/data"},
+		},
+		{
+			name:       "folder sub path",
+			uploadPath: "/data",
+			opts:       &RestoreOptions{SubPath: "home/user/docs"},
+			wantArgs:   []string{"4bb8d4cb:/data/home/user/docs"},
+		},
+		{
+			name:       "file sub path",
+			uploadPath: "/data",
+			opts:       &RestoreOptions{SubPath: "home/user/notes.txt", Overwrite: OverwriteNever},
+			wantArgs:   []string{"--overwrite", "never", "--include", "/notes.txt", "4bb8d4cb:/data/home/user"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var r = fakeRestic(t, stdout, "", 0)
+			summary, err := r.Restore("4bb8d4cb", tt.uploadPath, "/restore", tt.opts)
+			if err != nil {
+				t.Fatalf("Restore() error = %v", err)
+			}
+			if summary == nil || summary.FilesRestored != 1 {
+				t.Errorf("Restore() = %+v, want one restored file", summary)
+			}
+			var want = append(append([]string{}, prefix...), tt.wantArgs...)
+			if args := fakeArgs(t, r); !reflect.DeepEqual(args, want) {
+				t.Errorf("args = %q, want %q", args, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,139 @@
+package restic
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+
+	"bytetrade.io/web3os/uploader-sdk/pkg/util/logger"
+)
+
+// SnapshotFilter narrows the snapshots of a repository. Every entry of Tags
+// is a comma separated list that must all match, entries are ORed, and the
+// name=<name> tag of the repository is always required.
+type SnapshotFilter struct {
+	Tags  []string
+	Hosts []string
+	Paths []string
+	Since time.Time
+	Until time.Time
+}
+
+func (f *SnapshotFilter) args(name string) []string {
+	var args []string
+
+	if f == nil || len(f.Tags) == 0 {
+		args = append(args, "--tag", nameTag(name))
+	} else {
+		for _, tag := range f.Tags {
+			args = append(args, "--tag", nameTag(name)+","+tag)
+		}
+	}
+
+	if f == nil {
+		return args
+	}
+
+	for _, host := range f.Hosts {
+		args = append(args, "--host", host)
+	}
+	for _, path := range f.Paths {
+		args = append(args, "--path", path)
+	}
+
+	return args
+}
+
+func (f *SnapshotFilter) match(snapshot *Snapshot) bool {
+	if f == nil || (f.Since.IsZero() && f.Until.IsZero()) {
+		return true
+	}
+
+	t, err := snapshot.GetTime()
+	if err != nil {
+		return false
+	}
+	if !f.Since.IsZero() && t.Before(f.Since) {
+		return false
+	}
+	if !f.Until.IsZero() && t.After(f.Until) {
+		return false
+	}
+	return true
+}
+
+func (r *resticManager) ListSnapshots(filter *SnapshotFilter) ([]*Snapshot, error) {
+	var args = []string{
+		"snapshots",
+		PARAM_JSON_OUTPUT,
+		PARAM_INSECURE_TLS,
+	}
+	args = append(args, filter.args(r.name)...)
+
+	var snapshots []*Snapshot
+	err := r.run(args, func(res []byte) error {
+		var msg = string(res)
+		if strings.Contains(msg, "Fatal: ") {
+			logger.Debugf("[restic] snapshots %s error message: %s", r.name, msg)
+			return classify(msg)
+		}
+		return json.Unmarshal(res, &snapshots)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var result = make([]*Snapshot, 0, len(snapshots))
+	for _, snapshot := range snapshots {
+		if filter.match(snapshot) {
+			result = append(result, snapshot)
+		}
+	}
+
+	return result, nil
+}
+
+// Tag adds tags to the snapshots snapshotIds of the repository. restic saves
+// a tagged snapshot under a new id.
+func (r *resticManager) Tag(snapshotIds []string, tags ...string) error {
+	return r.tag("--add", snapshotIds, tags)
+}
+
+// Untag removes tags from the snapshots snapshotIds of the repository, the
+// name=<name> tag can not be removed.
+func (r *resticManager) Untag(snapshotIds []string, tags ...string) error {
+	return r.tag("--remove", snapshotIds, tags)
+}
+
+func (r *resticManager) tag(action string, snapshotIds []string, tags []string) error {
+	if len(snapshotIds) == 0 {
+		// restic tag changes every snapshot when none is given
+		return errors.New("no snapshots to tag")
+	}
+	if len(tags) == 0 {
+		return errors.New("no tags given")
+	}
+
+	var args = []string{
+		"tag",
+		PARAM_INSECURE_TLS,
+	}
+	args = append(args, r.withTag(r.name)...)
+	for _, tag := range tags {
+		if err := validTag(tag); err != nil {
+			return err
+		}
+		args = append(args, action, tag)
+	}
+	args = append(args, snapshotIds...)
+
+	return r.run(args, func(res []byte) error {
+		var msg = string(res)
+		logger.Debugf("[restic] tag %s message: %s", r.name, msg)
+		if strings.Contains(msg, "Fatal: ") {
+			return classify(msg)
+		}
+		return nil
+	})
+}
@@ -0,0 +1,118 @@
+package restic
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"bytetrade.io/web3os/uploader-sdk/pkg/util/logger"
+)
+
+// StatsMode is what restic stats counts.
+type StatsMode string
+
+const (
+	// StatsRestoreSize counts the size of the files as they are restored.
+	StatsRestoreSize StatsMode = "restore-size"
+	// StatsFilesByContents counts the size of the unique files.
+	StatsFilesByContents StatsMode = "files-by-contents"
+	// StatsRawData counts the size of the blobs stored in the repository,
+	// after deduplication and compression.
+	StatsRawData StatsMode = "raw-data"
+)
+
+// Stats is the output of restic stats, the compression fields are only set
+// in StatsRawData mode of repositories of version 2.
+type Stats struct {
+	TotalSize              uint64  `json:"total_size"`
+	TotalUncompressedSize  uint64  `json:"total_uncompressed_size,omitempty"`
+	TotalFileCount         uint64  `json:"total_file_count,omitempty"`
+	TotalBlobCount         uint64  `json:"total_blob_count,omitempty"`
+	SnapshotsCount         int     `json:"snapshots_count"`
+	CompressionRatio       float64 `json:"compression_ratio,omitempty"`
+	CompressionProgress    float64 `json:"compression_progress,omitempty"`
+	CompressionSpaceSaving float64 `json:"compression_space_saving,omitempty"`
+}
+
+// RepoUsage is the storage a repository consumes.
+type RepoUsage struct {
+	SnapshotsCount int `json:"snapshots_count"`
+	// RestoreSize is the size of the files of all snapshots.
+	RestoreSize uint64 `json:"restore_size"`
+	// StoredSize is the size of the blobs the snapshots reference, after
+	// deduplication and compression. It is a lower bound of the storage
+	// quota used: pack headers, indexes and the data of forgotten snapshots
+	// that were not pruned yet are stored as well.
+	StoredSize       uint64 `json:"stored_size"`
+	UncompressedSize uint64 `json:"uncompressed_size"`
+	// DeduplicationRatio is RestoreSize divided by UncompressedSize, and
+	// CompressionRatio UncompressedSize divided by StoredSize.
+	DeduplicationRatio float64 `json:"deduplication_ratio"`
+	CompressionRatio   float64 `json:"compression_ratio"`
+}
+
+// NewRepoUsage combines the StatsRestoreSize and StatsRawData stats of a
+// repository.
+func NewRepoUsage(restoreSize *Stats, rawData *Stats) *RepoUsage {
+	var usage = &RepoUsage{
+		SnapshotsCount:   restoreSize.SnapshotsCount,
+		RestoreSize:      restoreSize.TotalSize,
+		StoredSize:       rawData.TotalSize,
+		UncompressedSize: rawData.TotalUncompressedSize,
+		CompressionRatio: rawData.CompressionRatio,
+	}
+	if usage.UncompressedSize == 0 {
+		// repositories of version 1 are not compressed
+		usage.UncompressedSize = usage.StoredSize
+		usage.CompressionRatio = 1
+	}
+	if usage.UncompressedSize > 0 {
+		usage.DeduplicationRatio = float64(usage.RestoreSize) / float64(usage.UncompressedSize)
+	}
+
+	return usage
+}
+
+// Stats counts the snapshots of the repository in mode, only the given
+// snapshots when snapshotIds is not empty.
+func (r *resticManager) Stats(mode StatsMode, snapshotIds ...string) (*Stats, error) {
+	switch mode {
+	case "":
+		mode = StatsRestoreSize
+	case StatsRestoreSize, StatsFilesByContents, StatsRawData:
+	default:
+		return nil, fmt.Errorf("unknown stats mode %q", mode)
+	}
+
+	var args = []string{
+		"stats",
+		PARAM_JSON_OUTPUT,
+		PARAM_INSECURE_TLS,
+		"--mode",
+		string(mode),
+	}
+	args = append(args, r.withTag(r.name)...)
+	args = append(args, snapshotIds...)
+
+	var stats *Stats
+	err := r.run(args, func(res []byte) error {
+		var msg = string(res)
+		if strings.Contains(msg, "Fatal: ") {
+			logger.Debugf("[restic] stats %s error message: %s", r.name, msg)
+			return classify(msg)
+		}
+		if !strings.HasPrefix(strings.TrimSpace(msg), "{") {
+			logger.Debugf("[restic] stats %s message: %s", r.name, msg)
+			return nil
+		}
+		return json.Unmarshal(res, &stats)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if stats == nil {
+		return nil, fmt.Errorf("restic stats of %s printed no result", r.name)
+	}
+
+	return stats, nil
+}
@@ -0,0 +1,92 @@
+package restic
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestStats(t *testing.T) {
+	tests := []struct {
+		name   string
+		mode   StatsMode
+		stdout string
+		stderr string
+		want   *Stats
+	}{
+		{
+			name:   "restore size",
+			mode:   StatsRestoreSize,
+			stdout: `{"total_size":5242880,"total_file_count":120,"snapshots_count":3}` + "\n",
+			stderr: "scanning...\n",
+			want:   &Stats{TotalSize: 5242880, TotalFileCount: 120, SnapshotsCount: 3},
+		},
+		{
+			name:   "raw data",
+			mode:   StatsRawData,
+			stdout: `{"total_size":1048576,"total_uncompressed_size":2097152,"compression_ratio":2,"compression_progress":100,"compression_space_saving":50,"total_blob_count":42,"snapshots_count":3}` + "\n",
+			want: &Stats{
+				TotalSize:              1048576,
+				TotalUncompressedSize:  2097152,
+				TotalBlobCount:         42,
+				SnapshotsCount:         3,
+				CompressionRatio:       2,
+				CompressionProgress:    100,
+				CompressionSpaceSaving: 50,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := fakeRestic(t, tt.stdout, tt.stderr, 0).Stats(tt.mode)
+			if err != nil {
+				t.Fatalf("Stats() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Stats() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewRepoUsage(t *testing.T) {
+	tests := []struct {
+		name        string
+		restoreSize *Stats
+		rawData     *Stats
+		want        *RepoUsage
+	}{
+		{
+			name:        "compressed",
+			restoreSize: &Stats{TotalSize: 4000, SnapshotsCount: 3},
+			rawData:     &Stats{TotalSize: 500, TotalUncompressedSize: 1000, CompressionRatio: 2, SnapshotsCount: 3},
+			want: &RepoUsage{
+				SnapshotsCount:     3,
+				RestoreSize:        4000,
+				StoredSize:         500,
+				UncompressedSize:   1000,
+				DeduplicationRatio: 4,
+				CompressionRatio:   2,
+			},
+		},
+		{
+			name:        "repository version 1",
+			restoreSize: &Stats{TotalSize: 4000, SnapshotsCount: 3},
+			rawData:     &Stats{TotalSize: 1000, SnapshotsCount: 3},
+			want: &RepoUsage{
+				SnapshotsCount:     3,
+				RestoreSize:        4000,
+				StoredSize:         1000,
+				UncompressedSize:   1000,
+				DeduplicationRatio: 4,
+				CompressionRatio:   1,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := NewRepoUsage(tt.restoreSize, tt.rawData); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("NewRepoUsage() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
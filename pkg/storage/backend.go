@@ -0,0 +1,143 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"bytetrade.io/web3os/uploader-sdk/pkg/util"
+	"bytetrade.io/web3os/uploader-sdk/pkg/util/logger"
+)
+
+// Backend provides the location and the credentials of the restic
+// repositories a StorageClient works on. Every repository name maps to its
+// own repository below the location of the backend.
+type Backend interface {
+	// Prepare obtains the credentials before the first restic command runs.
+	Prepare(ctx context.Context) error
+	// Refresh renews the credentials after restic reported them expired.
+	Refresh(ctx context.Context) error
+	RepoUrl(name string) string
+	// RepoEnv returns the environment restic needs to access the repository,
+	// the repository password is added by the StorageClient.
+	RepoEnv(name string) map[string]string
+}
+
+var (
+	_ Backend = &OlaresSpace{}
+	_ Backend = &LocalBackend{}
+	_ Backend = &S3Backend{}
+	_ Backend = &RestBackend{}
+)
+
+// LocalBackend stores repositories in a directory of the local filesystem,
+// e.g. a mounted NAS share.
+type LocalBackend struct {
+	Path string `json:"path"`
+}
+
+func (b *LocalBackend) Prepare(ctx context.Context) error {
+	if b.Path == "" {
+		return fmt.Errorf("local backend path is empty")
+	}
+	return nil
+}
+
+func (b *LocalBackend) Refresh(ctx context.Context) error {
+	return fmt.Errorf("local backend credentials can not be refreshed")
+}
+
+func (b *LocalBackend) RepoUrl(name string) string {
+	return filepath.Join(b.Path, name)
+}
+
+func (b *LocalBackend) RepoEnv(name string) map[string]string {
+	return map[string]string{
+		"RESTIC_REPOSITORY": b.RepoUrl(name),
+	}
+}
+
+// S3Backend stores repositories in an S3 compatible bucket, such as MinIO,
+// using static access keys.
+type S3Backend struct {
+	// Endpoint is the host of the service, e.g. "s3.amazonaws.com" or
+	// "http://minio.local:9000" when plain http is used.
+	Endpoint  string      `json:"endpoint"`
+	Bucket    string      `json:"bucket"`
+	Prefix    string      `json:"prefix"`
+	Region    string      `json:"region"`
+	AccessKey string      `json:"access_key"`
+	SecretKey util.Secret `json:"secret_key"`
+}
+
+func (b *S3Backend) Prepare(ctx context.Context) error {
+	if b.Endpoint == "" || b.Bucket == "" {
+		return fmt.Errorf("s3 backend endpoint or bucket is empty")
+	}
+	logger.RegisterSecret(b.SecretKey.Value())
+	return nil
+}
+
+func (b *S3Backend) Refresh(ctx context.Context) error {
+	return fmt.Errorf("s3 backend credentials can not be refreshed")
+}
+
+func (b *S3Backend) RepoUrl(name string) string {
+	return fmt.Sprintf("s3:%s/%s", strings.TrimRight(b.Endpoint, "/"), path.Join(b.Bucket, b.Prefix, name))
+}
+
+func (b *S3Backend) RepoEnv(name string) map[string]string {
+	var env = map[string]string{
+		"AWS_ACCESS_KEY_ID":     b.AccessKey,
+		"AWS_SECRET_ACCESS_KEY": b.SecretKey.Value(),
+		"RESTIC_REPOSITORY":     b.RepoUrl(name),
+	}
+	if b.Region != "" {
+		env["AWS_DEFAULT_REGION"] = b.Region
+	}
+	return env
+}
+
+// RestBackend stores repositories on a restic rest-server.
+type RestBackend struct {
+	// Url of the rest-server, e.g. "https://backup.local:8000".
+	Url      string      `json:"url"`
+	Username string      `json:"username"`
+	Password util.Secret `json:"password"`
+}
+
+func (b *RestBackend) Prepare(ctx context.Context) error {
+	if _, err := url.Parse(b.Url); err != nil || b.Url == "" {
+		return fmt.Errorf("rest backend url %q invalid", b.Url)
+	}
+	// the repository url carries the password escaped
+	var escaped = strings.TrimPrefix(url.UserPassword("", b.Password.Value()).String(), ":")
+	logger.RegisterSecret(b.Password.Value(), escaped)
+	return nil
+}
+
+func (b *RestBackend) Refresh(ctx context.Context) error {
+	return fmt.Errorf("rest backend credentials can not be refreshed")
+}
+
+func (b *RestBackend) RepoUrl(name string) string {
+	u, err := url.Parse(b.Url)
+	if err != nil {
+		return fmt.Sprintf("rest:%s/%s", strings.TrimRight(b.Url, "/"), name)
+	}
+	if b.Username != "" {
+		u.User = url.UserPassword(b.Username, b.Password.Value())
+	}
+	u.Path = path.Join("/", u.Path, name) + "/"
+
+	return fmt.Sprintf("rest:%s", u.String())
+}
+
+func (b *RestBackend) RepoEnv(name string) map[string]string {
+	return map[string]string{
+		"RESTIC_REPOSITORY": b.RepoUrl(name),
+	}
+}
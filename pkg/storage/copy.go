@@ -0,0 +1,235 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"bytetrade.io/web3os/uploader-sdk/pkg/restic"
+	"bytetrade.io/web3os/uploader-sdk/pkg/util/logger"
+)
+
+// ErrCopyConflict is returned when the source and the destination of a copy
+// need different credentials in the same variables. restic reads the
+// credentials of both repositories from them, so it can not copy between two
+// S3 accounts or two Olares Spaces, whose tokens always differ. CopyTo copies
+// such repositories through a local repository instead.
+var ErrCopyConflict = errors.New("restic can not copy between repositories that need different credentials")
+
+// copyBackend runs restic against the destination of a copy with the source
+// repository set as RESTIC_FROM_REPOSITORY, a copy between backends whose
+// credentials conflict is refused with ErrCopyConflict. Both repositories use
+// the same password, the StorageClient adds it for the source as well.
+type copyBackend struct {
+	src  Backend
+	dst  Backend
+	name string
+}
+
+func (b *copyBackend) Prepare(ctx context.Context) error {
+	if err := b.src.Prepare(ctx); err != nil {
+		return err
+	}
+	if err := b.dst.Prepare(ctx); err != nil {
+		return err
+	}
+	return b.conflict()
+}
+
+// Refresh renews the credentials of the backends that expire.
+func (b *copyBackend) Refresh(ctx context.Context) error {
+	var refreshed bool
+	for _, backend := range []Backend{b.src, b.dst} {
+		if _, ok := backend.(Expirer); !ok {
+			continue
+		}
+		if err := backend.Refresh(ctx); err != nil {
+			return err
+		}
+		refreshed = true
+	}
+	if !refreshed {
+		return fmt.Errorf("copy backend credentials can not be refreshed")
+	}
+	return b.conflict()
+}
+
+func (b *copyBackend) ExpiresAt() (time.Time, bool) {
+	var expiresAt time.Time
+	var found bool
+	for _, backend := range []Backend{b.src, b.dst} {
+		expirer, ok := backend.(Expirer)
+		if !ok {
+			continue
+		}
+		t, ok := expirer.ExpiresAt()
+		if ok && (!found || t.Before(expiresAt)) {
+			expiresAt, found = t, true
+		}
+	}
+	return expiresAt, found
+}
+
+func (b *copyBackend) RepoUrl(name string) string {
+	return b.dst.RepoUrl(name)
+}
+
+func (b *copyBackend) RepoEnv(name string) map[string]string {
+	var env = b.dst.RepoEnv(name)
+	for k, v := range b.src.RepoEnv(name) {
+		if k == "RESTIC_REPOSITORY" {
+			continue
+		}
+		env[k] = v
+	}
+	env["RESTIC_FROM_REPOSITORY"] = b.src.RepoUrl(name)
+
+	return env
+}
+
+func (b *copyBackend) conflict() error {
+	var dstEnv = b.dst.RepoEnv(b.name)
+	for k, v := range b.src.RepoEnv(b.name) {
+		if k == "RESTIC_REPOSITORY" {
+			continue
+		}
+		if d, ok := dstEnv[k]; ok && d != v {
+			return fmt.Errorf("%w: source and destination need different values for %s", ErrCopyConflict, k)
+		}
+	}
+	return nil
+}
+
+// CopyTo copies the snapshots of s.Name that match filter to the repository
+// of the same name in dst, initializing it when needed. Both repositories use
+// the password of s. Snapshots dst already has a copy of are skipped, so an
+// interrupted copy resumes where it stopped. Repositories that need
+// different credentials, such as two Olares Spaces, are copied in two passes
+// through a temporary local repository, which needs the disk space of the
+// copied snapshots.
+func (s *StorageClient) CopyTo(ctx context.Context, dst Backend, filter *restic.SnapshotFilter) (*restic.CopyReport, error) {
+	if dst == nil {
+		return nil, fmt.Errorf("copy destination backend is nil")
+	}
+	var src = s.backend()
+
+	snapshots, err := s.ListSnapshots(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(snapshots) == 0 {
+		logger.Infof("no snapshots of %s to copy", s.Name)
+		return restic.NewCopyReport(), nil
+	}
+	var snapshotIds = make([]string, 0, len(snapshots))
+	for _, snapshot := range snapshots {
+		snapshotIds = append(snapshotIds, snapshot.Id)
+	}
+
+	report, err := s.copy(ctx, src, dst, snapshotIds)
+	if errors.Is(err, ErrCopyConflict) {
+		logger.Infof("copy %s through a local repository: %v", s.Name, err)
+		report, err = s.copyStaged(ctx, src, dst, snapshotIds)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	logger.Infof("copy %s finished, copied: %d, skipped: %d", s.Name, len(report.Copied), len(report.Skipped))
+
+	return report, nil
+}
+
+// copyStaged copies the snapshots from src to a temporary local repository
+// and from there to dst, each pass with the credentials of one side only.
+func (s *StorageClient) copyStaged(ctx context.Context, src, dst Backend, snapshotIds []string) (*restic.CopyReport, error) {
+	dir, err := os.MkdirTemp("", "restic-copy-")
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err := os.RemoveAll(dir); err != nil {
+			logger.Warnf("remove copy staging repository %s error: %v", dir, err)
+		}
+	}()
+	var stage = &LocalBackend{Path: dir}
+
+	staged, err := s.copy(ctx, src, stage, snapshotIds)
+	if err != nil {
+		return nil, err
+	}
+	var stagedIds = make([]string, 0, len(staged.Copied))
+	for _, id := range staged.Copied {
+		stagedIds = append(stagedIds, id)
+	}
+	copied, err := s.copy(ctx, stage, dst, stagedIds)
+	if err != nil {
+		return nil, err
+	}
+
+	return joinCopyReports(staged, copied), nil
+}
+
+// joinCopyReports maps the source snapshots of first to the copies second
+// made of their copies.
+func joinCopyReports(first, second *restic.CopyReport) *restic.CopyReport {
+	var report = restic.NewCopyReport()
+	for src, staged := range first.Copied {
+		if dst, ok := second.Copied[staged]; ok {
+			report.Copied[src] = dst
+		} else if dst, ok := second.Skipped[staged]; ok {
+			report.Skipped[src] = dst
+		}
+	}
+	return report
+}
+
+// copy copies the snapshots snapshotIds from src to dst with a single restic
+// copy, it fails with ErrCopyConflict when their credentials conflict.
+func (s *StorageClient) copy(ctx context.Context, src, dst Backend, snapshotIds []string) (*restic.CopyReport, error) {
+	var copier = &StorageClient{
+		Name:               s.Name,
+		UserName:           s.UserName,
+		Password:           s.Password,
+		PasswordProvider:   s.PasswordProvider,
+		Progress:           s.Progress,
+		TokenRefreshMargin: s.TokenRefreshMargin,
+		Backend: &copyBackend{
+			src:  src,
+			dst:  dst,
+			name: s.Name,
+		},
+	}
+
+	var report = restic.NewCopyReport()
+	_, err := copier.runRestic(ctx, &restic.Option{Progress: s.Progress}, runOptions{resumable: true}, func(r restic.Restic) error {
+		s.Progress.Phase(restic.PhaseInit)
+		if _, err := r.Init(); err != nil && !errors.Is(err, restic.ErrRepoAlreadyInitialized) {
+			return err
+		}
+
+		s.Progress.Phase(restic.PhaseCopy)
+		copied, err := r.Copy(snapshotIds)
+		if err != nil {
+			return err
+		}
+		// a run restarted for a token refresh skips what it copied before
+		for src, dst := range copied.Copied {
+			report.Copied[src] = dst
+		}
+		for src, dst := range copied.Skipped {
+			if _, ok := report.Copied[src]; !ok {
+				report.Skipped[src] = dst
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return report, nil
+}
@@ -0,0 +1,81 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"bytetrade.io/web3os/uploader-sdk/pkg/restic"
+	"bytetrade.io/web3os/uploader-sdk/pkg/util"
+)
+
+func TestCopyBackendEnv(t *testing.T) {
+	var src = &S3Backend{Endpoint: "s3.amazonaws.com", Bucket: "backup", AccessKey: "key", SecretKey: util.Secret("secret")}
+
+	tests := []struct {
+		name     string
+		dst      Backend
+		conflict bool
+	}{
+		{name: "local", dst: &LocalBackend{Path: "/mnt/nas"}},
+		{name: "same s3 account", dst: &S3Backend{Endpoint: "s3.amazonaws.com", Bucket: "replica", AccessKey: "key", SecretKey: util.Secret("secret")}},
+		{name: "other s3 account", dst: &S3Backend{Endpoint: "s3.amazonaws.com", Bucket: "replica", AccessKey: "other", SecretKey: util.Secret("secret")}, conflict: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var b = &copyBackend{src: src, dst: tt.dst, name: "olares"}
+			err := b.Prepare(context.Background())
+			if errors.Is(err, ErrCopyConflict) != tt.conflict {
+				t.Fatalf("Prepare() error = %v, want conflict %v", err, tt.conflict)
+			}
+			if tt.conflict {
+				return
+			}
+
+			// the password is asked for every run
+			var asked int
+			var s = &StorageClient{Name: "olares", PasswordProvider: PasswordFunc(func(ctx context.Context) (string, error) {
+				asked++
+				return fmt.Sprintf("pass%d", asked), nil
+			})}
+			if _, _, err := s.repoEnv(context.Background(), b); err != nil {
+				t.Fatalf("repoEnv() error = %v", err)
+			}
+			env, _, err := s.repoEnv(context.Background(), b)
+			if err != nil {
+				t.Fatalf("repoEnv() error = %v", err)
+			}
+			if env["RESTIC_REPOSITORY"] != tt.dst.RepoUrl("olares") {
+				t.Errorf("RESTIC_REPOSITORY = %q, want %q", env["RESTIC_REPOSITORY"], tt.dst.RepoUrl("olares"))
+			}
+			if env["RESTIC_FROM_REPOSITORY"] != src.RepoUrl("olares") {
+				t.Errorf("RESTIC_FROM_REPOSITORY = %q, want %q", env["RESTIC_FROM_REPOSITORY"], src.RepoUrl("olares"))
+			}
+			if env["RESTIC_PASSWORD"] != "pass2" || env["RESTIC_FROM_PASSWORD"] != "pass2" || env["AWS_ACCESS_KEY_ID"] != "key" {
+				t.Errorf("env = %s, want the source password and access key", util.RedactEnv(env))
+			}
+		})
+	}
+}
+
+func TestJoinCopyReports(t *testing.T) {
+	// src -> local staging repository -> dst
+	var staged = &restic.CopyReport{
+		Copied:  map[string]string{"4bb8d4cb": "11111111", "2c3d4e5f": "22222222"},
+		Skipped: map[string]string{},
+	}
+	var copied = &restic.CopyReport{
+		Copied:  map[string]string{"22222222": "8a9b0c1d"},
+		Skipped: map[string]string{"11111111": "7e6f5a4b"},
+	}
+
+	var want = &restic.CopyReport{
+		Copied:  map[string]string{"2c3d4e5f": "8a9b0c1d"},
+		Skipped: map[string]string{"4bb8d4cb": "7e6f5a4b"},
+	}
+	if got := joinCopyReports(staged, copied); !reflect.DeepEqual(got, want) {
+		t.Errorf("joinCopyReports() = %+v, want %+v", got, want)
+	}
+}
@@ -0,0 +1,103 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+
+	"bytetrade.io/web3os/uploader-sdk/pkg/restic"
+	"bytetrade.io/web3os/uploader-sdk/pkg/util/logger"
+)
+
+func (s *StorageClient) ListKeys(ctx context.Context) ([]*restic.Key, error) {
+	var keys []*restic.Key
+
+	err := s.withRestic(ctx, &restic.Option{}, func(r restic.Restic) error {
+		var err error
+		keys, err = r.ListKeys()
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return keys, nil
+}
+
+// RotatePassword replaces the key of the current password with a key of
+// newPassword. The new key is added and must open the repository before the
+// old key is removed, a new key that does not work is removed again. On
+// success s uses newPassword, unless it has a PasswordProvider whose source
+// the caller updates instead.
+func (s *StorageClient) RotatePassword(ctx context.Context, newPassword string) error {
+	if newPassword == "" {
+		return fmt.Errorf("new repository password is empty")
+	}
+	if s.PasswordProvider == nil && newPassword == s.Password {
+		return fmt.Errorf("new repository password is the current one")
+	}
+	// both clients share the backend, so its credentials are fetched once
+	var backend = s.backend()
+	var current = s.withPassword(backend, s.password())
+	var next = s.withPassword(backend, StaticPassword(newPassword))
+
+	var oldId, newId string
+	err := current.withRestic(ctx, &restic.Option{}, func(r restic.Restic) error {
+		keys, err := r.ListKeys()
+		if err != nil {
+			return err
+		}
+		for _, key := range keys {
+			if key.Current {
+				oldId = key.Id
+			}
+		}
+		if oldId == "" {
+			return fmt.Errorf("key of the current password of %s not found", s.Name)
+		}
+
+		if newId == "" {
+			newId, err = r.AddKey(newPassword)
+		}
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	logger.Infof("added key %s to %s, verify it", newId, s.Name)
+
+	err = next.withRestic(ctx, &restic.Option{}, func(r restic.Restic) error {
+		keys, err := r.ListKeys()
+		if err != nil {
+			return err
+		}
+		for _, key := range keys {
+			if key.Current && key.Is(newId) {
+				return r.RemoveKey(oldId)
+			}
+		}
+		return fmt.Errorf("new key %s does not open %s", newId, s.Name)
+	})
+	if err != nil {
+		logger.Warnf("rotate password of %s failed, remove the new key %s: %v", s.Name, newId, err)
+		if rollbackErr := current.withRestic(ctx, &restic.Option{}, func(r restic.Restic) error {
+			return r.RemoveKey(newId)
+		}); rollbackErr != nil {
+			logger.Errorf("remove the new key %s of %s failed: %v", newId, s.Name, rollbackErr)
+		}
+		return err
+	}
+
+	logger.Infof("rotated password of %s, removed key %s", s.Name, oldId)
+	if s.PasswordProvider == nil {
+		s.Password = newPassword
+	}
+
+	return nil
+}
+
+func (s *StorageClient) withPassword(backend Backend, password PasswordProvider) *StorageClient {
+	var c = *s
+	c.Backend = backend
+	c.PasswordProvider = password
+	return &c
+}
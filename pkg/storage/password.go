@@ -0,0 +1,135 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"bytetrade.io/web3os/uploader-sdk/pkg/client"
+	"bytetrade.io/web3os/uploader-sdk/pkg/util/logger"
+	"github.com/pkg/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// PasswordProvider hands the repository password to restic. It is asked
+// before every restic run, so a password read by the SDK is only kept for
+// the run.
+type PasswordProvider interface {
+	// PasswordEnv returns the RESTIC_PASSWORD, RESTIC_PASSWORD_FILE or
+	// RESTIC_PASSWORD_COMMAND variable restic reads the password from.
+	PasswordEnv(ctx context.Context) (map[string]string, error)
+}
+
+var (
+	_ PasswordProvider = StaticPassword("")
+	_ PasswordProvider = &PasswordFile{}
+	_ PasswordProvider = &PasswordCommand{}
+	_ PasswordProvider = &KubeSecretPassword{}
+	_ PasswordProvider = PasswordFunc(nil)
+)
+
+// StaticPassword is a password held in memory, it backs the Password field
+// of the clients. It is held as long as the client, so it is masked in the
+// logs as well.
+type StaticPassword string
+
+func (p StaticPassword) PasswordEnv(ctx context.Context) (map[string]string, error) {
+	logger.RegisterSecret(string(p))
+	return passwordEnv(string(p))
+}
+
+// PasswordFile lets restic read the password from a file, the SDK never
+// reads it.
+type PasswordFile struct {
+	Path string `json:"path"`
+}
+
+func (p *PasswordFile) PasswordEnv(ctx context.Context) (map[string]string, error) {
+	if p.Path == "" {
+		return nil, fmt.Errorf("password file path is empty")
+	}
+	return map[string]string{"RESTIC_PASSWORD_FILE": p.Path}, nil
+}
+
+// PasswordCommand lets restic run a command that prints the password, e.g.
+// "pass show backup", the SDK never sees it.
+type PasswordCommand struct {
+	Command string `json:"command"`
+}
+
+func (p *PasswordCommand) PasswordEnv(ctx context.Context) (map[string]string, error) {
+	if p.Command == "" {
+		return nil, fmt.Errorf("password command is empty")
+	}
+	return map[string]string{"RESTIC_PASSWORD_COMMAND": p.Command}, nil
+}
+
+// KubeSecretPassword reads the password from the key Key of a Kubernetes
+// secret.
+type KubeSecretPassword struct {
+	Namespace string `json:"namespace"`
+	Name      string `json:"name"`
+	Key       string `json:"key"`
+}
+
+func (p *KubeSecretPassword) PasswordEnv(ctx context.Context) (map[string]string, error) {
+	factory, err := client.NewFactory()
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	kubeClient, err := factory.KubeClient()
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	secret, err := kubeClient.CoreV1().Secrets(p.Namespace).Get(ctx, p.Name, metav1.GetOptions{})
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	password, ok := secret.Data[p.Key]
+	if !ok {
+		return nil, fmt.Errorf("key %s not found in secret %s/%s", p.Key, p.Namespace, p.Name)
+	}
+
+	return passwordEnv(string(password))
+}
+
+// PasswordFunc returns the password, e.g. from a vault.
+type PasswordFunc func(ctx context.Context) (string, error)
+
+func (f PasswordFunc) PasswordEnv(ctx context.Context) (map[string]string, error) {
+	if f == nil {
+		return nil, fmt.Errorf("password func is nil")
+	}
+	password, err := f(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return passwordEnv(password)
+}
+
+// passwordEnv hands password to restic. The environment is only logged with
+// its RESTIC_PASSWORD masked, so a password read for a single run is not
+// registered as a secret of the logger, which would keep it for good.
+func passwordEnv(password string) (map[string]string, error) {
+	if password == "" {
+		return nil, fmt.Errorf("repository password is empty")
+	}
+	return map[string]string{"RESTIC_PASSWORD": password}, nil
+}
+
+// fromPasswordEnv turns the password variables of a repository into the
+// ones restic reads for the source repository of a copy.
+func fromPasswordEnv(env map[string]string) map[string]string {
+	var from = make(map[string]string, len(env))
+	for k, v := range env {
+		from[strings.Replace(k, "RESTIC_", "RESTIC_FROM_", 1)] = v
+	}
+	return from
+}
@@ -0,0 +1,89 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"bytetrade.io/web3os/uploader-sdk/pkg/util/logger"
+)
+
+const (
+	defaultRefreshMargin = 10 * time.Minute
+	// minRestartInterval keeps a restic command from being restarted over
+	// and over when the backend hands out very short lived credentials.
+	minRestartInterval = 5 * time.Minute
+)
+
+var errRestartForRefresh = errors.New("restart to refresh storage token")
+
+// afterFunc starts the restart timer of schedule, tests replace it to not
+// wait for minutes.
+var afterFunc = time.AfterFunc
+
+// Expirer is implemented by backends whose credentials expire, such as the
+// STS tokens of Olares Space.
+type Expirer interface {
+	ExpiresAt() (time.Time, bool)
+}
+
+// tokenManager refreshes the credentials of a backend ahead of their
+// expiration, so restic never runs into an expired token.
+type tokenManager struct {
+	backend Backend
+	margin  time.Duration
+}
+
+func newTokenManager(backend Backend, margin time.Duration) *tokenManager {
+	if margin <= 0 {
+		margin = defaultRefreshMargin
+	}
+	return &tokenManager{backend: backend, margin: margin}
+}
+
+func (m *tokenManager) refreshAt() (time.Time, bool) {
+	expirer, ok := m.backend.(Expirer)
+	if !ok {
+		return time.Time{}, false
+	}
+	expiresAt, ok := expirer.ExpiresAt()
+	if !ok {
+		return time.Time{}, false
+	}
+	return expiresAt.Add(-m.margin), true
+}
+
+// ensureFresh refreshes the credentials when they expire within the margin.
+func (m *tokenManager) ensureFresh(ctx context.Context) error {
+	refreshAt, ok := m.refreshAt()
+	if !ok || time.Now().Before(refreshAt) {
+		return nil
+	}
+
+	logger.Infof("storage token expires at %s, refresh", refreshAt.Add(m.margin).Format(time.RFC3339))
+	return m.backend.Refresh(ctx)
+}
+
+// schedule returns a context derived from ctx that is cancelled with
+// errRestartForRefresh shortly before the credentials expire, so a long
+// running command stops at a point where it can be resumed with fresh
+// credentials instead of failing halfway.
+func (m *tokenManager) schedule(ctx context.Context) (context.Context, context.CancelFunc) {
+	runCtx, cancel := context.WithCancelCause(ctx)
+
+	refreshAt, ok := m.refreshAt()
+	var wait = time.Until(refreshAt)
+	if !ok || wait < minRestartInterval {
+		return runCtx, func() { cancel(nil) }
+	}
+
+	timer := afterFunc(wait, func() {
+		logger.Infof("storage token is about to expire, restart restic to refresh it")
+		cancel(errRestartForRefresh)
+	})
+
+	return runCtx, func() {
+		timer.Stop()
+		cancel(nil)
+	}
+}
@@ -0,0 +1,111 @@
+package storage
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"bytetrade.io/web3os/uploader-sdk/pkg/util/logger"
+	"go.uber.org/zap"
+)
+
+func init() {
+	logger.SetLogger(zap.NewNop().Sugar())
+}
+
+// expiringBackend is a Backend whose credentials expire at expiresAt, every
+// Refresh moves the expiration by an hour.
+type expiringBackend struct {
+	LocalBackend
+	expiresAt time.Time
+	refreshed int
+}
+
+func (b *expiringBackend) Refresh(ctx context.Context) error {
+	b.refreshed++
+	b.expiresAt = time.Now().Add(time.Hour)
+	return nil
+}
+
+func (b *expiringBackend) ExpiresAt() (time.Time, bool) {
+	return b.expiresAt, !b.expiresAt.IsZero()
+}
+
+func TestEnsureFresh(t *testing.T) {
+	tests := []struct {
+		name          string
+		expiresIn     time.Duration
+		wantRefreshed int
+	}{
+		{name: "no expiration", wantRefreshed: 0},
+		{name: "outside the margin", expiresIn: time.Hour, wantRefreshed: 0},
+		{name: "inside the margin", expiresIn: 5 * time.Minute, wantRefreshed: 1},
+		{name: "expired", expiresIn: -time.Minute, wantRefreshed: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var backend = &expiringBackend{}
+			if tt.expiresIn != 0 {
+				backend.expiresAt = time.Now().Add(tt.expiresIn)
+			}
+			if err := newTokenManager(backend, 0).ensureFresh(context.Background()); err != nil {
+				t.Fatalf("ensureFresh() error = %v", err)
+			}
+			if backend.refreshed != tt.wantRefreshed {
+				t.Errorf("ensureFresh() refreshed %d times, want %d", backend.refreshed, tt.wantRefreshed)
+			}
+		})
+	}
+}
+
+func TestSchedule(t *testing.T) {
+	var waits []time.Duration
+	afterFunc = func(d time.Duration, f func()) *time.Timer {
+		waits = append(waits, d)
+		return time.AfterFunc(0, f)
+	}
+	t.Cleanup(func() { afterFunc = time.AfterFunc })
+
+	t.Run("below the minimum restart interval", func(t *testing.T) {
+		waits = nil
+		var backend = &expiringBackend{expiresAt: time.Now().Add(defaultRefreshMargin + time.Minute)}
+		runCtx, cancel := newTokenManager(backend, 0).schedule(context.Background())
+		defer cancel()
+
+		if len(waits) != 0 {
+			t.Fatalf("schedule() started a restart timer after %v", waits)
+		}
+		if runCtx.Err() != nil {
+			t.Errorf("schedule() context is done: %v", context.Cause(runCtx))
+		}
+	})
+
+	t.Run("restart before expiration", func(t *testing.T) {
+		waits = nil
+		var backend = &expiringBackend{expiresAt: time.Now().Add(time.Hour)}
+		runCtx, cancel := newTokenManager(backend, 0).schedule(context.Background())
+		defer cancel()
+
+		select {
+		case <-runCtx.Done():
+		case <-time.After(5 * time.Second):
+			t.Fatal("schedule() context was not cancelled")
+		}
+		if cause := context.Cause(runCtx); cause != errRestartForRefresh {
+			t.Errorf("schedule() cancel cause = %v, want %v", cause, errRestartForRefresh)
+		}
+		if len(waits) != 1 || waits[0] > time.Hour-defaultRefreshMargin || waits[0] < time.Hour-defaultRefreshMargin-time.Minute {
+			t.Errorf("schedule() restart timer waits %v, want about %v", waits, time.Hour-defaultRefreshMargin)
+		}
+	})
+
+	t.Run("cancelled by the caller", func(t *testing.T) {
+		var backend = &expiringBackend{}
+		runCtx, cancel := newTokenManager(backend, 0).schedule(context.Background())
+		cancel()
+
+		if cause := context.Cause(runCtx); cause != context.Canceled {
+			t.Errorf("schedule() cancel cause = %v, want %v", cause, context.Canceled)
+		}
+	})
+}
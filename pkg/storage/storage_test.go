@@ -0,0 +1,55 @@
+package storage
+
+import (
+	"testing"
+
+	"bytetrade.io/web3os/uploader-sdk/pkg/restic"
+)
+
+func TestRestorePath(t *testing.T) {
+	tests := []struct {
+		name    string
+		paths   []string
+		opts    *restic.RestoreOptions
+		want    string
+		wantErr bool
+	}{
+		{
+			name:  "single path",
+			paths: []string{"/data/app"},
+			want:  "/data/app",
+		},
+		{
+			name:  "several paths",
+			paths: []string{"/data/app", "/etc/app"},
+			want:  "/",
+		},
+		{
+			name:  "picked path",
+			paths: []string{"/data/app", "/etc/app"},
+			opts:  &restic.RestoreOptions{Path: "etc/app/"},
+			want:  "/etc/app",
+		},
+		{
+			name:    "unknown path",
+			paths:   []string{"/data/app", "/etc/app"},
+			opts:    &restic.RestoreOptions{Path: "/var"},
+			wantErr: true,
+		},
+		{
+			name:    "no paths",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := restorePath(&restic.Snapshot{Id: "4bb8d4cb", Paths: tt.paths}, tt.opts)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("restorePath() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("restorePath() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
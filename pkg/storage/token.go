@@ -3,17 +3,19 @@ package storage
 import (
 	"context"
 	"crypto/tls"
+	stderrors "errors"
 	"strconv"
 
 	"fmt"
 	"net/http"
-	"path/filepath"
+	"path"
 	"strings"
 	"time"
 
 	"bytetrade.io/web3os/uploader-sdk/pkg/client"
 	"bytetrade.io/web3os/uploader-sdk/pkg/common"
 	"bytetrade.io/web3os/uploader-sdk/pkg/response"
+	"bytetrade.io/web3os/uploader-sdk/pkg/restic"
 	"bytetrade.io/web3os/uploader-sdk/pkg/util"
 	"bytetrade.io/web3os/uploader-sdk/pkg/util/logger"
 	"github.com/emicklei/go-restful/v3"
@@ -30,29 +32,30 @@ type OlaresSpace struct {
 	UserName    string `json:"user_name"`
 	AccountName string `json:"account_name"`
 
-	UserId    string `json:"user_id"`
-	UserToken string `json:"user_token"`
+	UserId    string      `json:"user_id"`
+	UserToken util.Secret `json:"user_token"`
 
 	CloudName          string              `json:"cloud_name"`
 	CloudRegion        string              `json:"cloud_region"`
 	UploadPath         string              `json:"upload_path"`
 	CloudApiMirror     string              `json:"cloud_api_mirror"`
+	CloudEndpoint      string              `json:"cloud_endpoint"`
 	Duration           string              `json:"duration"`
 	OlaresSpaceSession *OlaresSpaceSession `json:"olares_space_session"`
-	Env                map[string]string   `json:"env"`
+	Env                map[string]string   `json:"-"`
 }
 
 type OlaresSpaceSession struct {
-	Cloud      string `json:"cloud"` // "aws", "tencentcloud"
-	Bucket     string `json:"bucket"`
-	Token      string `json:"st"`
-	Prefix     string `json:"prefix"` // "fbcf5f573ed242c28758-342957450633", "did:key:???-55c06979be5e"
-	Secret     string `json:"sk"`
-	Key        string `json:"ak"`
-	Expiration string `json:"expiration"` // "1705550635000",
-	Region     string `json:"region"`     // "us-west-1", "ap-beijing"
-	RepoUrl    string `json:"repo_url"`
-	Password   string `json:"password"`
+	Cloud      string      `json:"cloud"` // "aws", "tencentcloud"
+	Bucket     string      `json:"bucket"`
+	Token      util.Secret `json:"st"`
+	Prefix     string      `json:"prefix"` // "fbcf5f573ed242c28758-342957450633", "did:key:???-55c06979be5e"
+	Secret     util.Secret `json:"sk"`
+	Key        string      `json:"ak"`
+	Expiration string      `json:"expiration"` // "1705550635000",
+	Region     string      `json:"region"`     // "us-west-1", "ap-beijing"
+	RepoUrl    string      `json:"repo_url"`
+	Password   util.Secret `json:"password"`
 }
 
 type AccountResponse struct {
@@ -84,13 +87,21 @@ type AccountValue struct {
 	Expired any    `json:"expired"`
 }
 
+// ErrSpaceNotEnabled is returned when the user has not added Olares Space
+// to the integrations of the Settings app.
+var ErrSpaceNotEnabled = stderrors.New("\nOlares Space is not enabled. Please go to the Settings - Integration page in the LarePass App to add Space\n")
+
 var UsersGVR = schema.GroupVersionResource{
 	Group:    "iam.kubesphere.io",
 	Version:  "v1alpha2",
 	Resource: "users",
 }
 
+// Expire parses Expiration, which the cloud returns in unix milliseconds.
 func (c *OlaresSpaceSession) Expire() (time.Time, error) {
+	if ms, err := strconv.ParseInt(c.Expiration, 10, 64); err == nil {
+		return time.UnixMilli(ms), nil
+	}
 	return time.Parse(time.RFC3339, c.Expiration)
 }
 
@@ -99,15 +110,82 @@ type CloudStorageAccountResponse struct {
 	Data *OlaresSpaceSession `json:"data"`
 }
 
-func (t *OlaresSpace) SetRepoUrl(name, password string) {
+func (t *OlaresSpace) Prepare(ctx context.Context) error {
+	if err := t.SetAccount(ctx); err != nil {
+		return fmt.Errorf("get account error: %w", err)
+	}
+
+	if err := t.RefreshToken(ctx, true); err != nil {
+		return err
+	}
+	logger.Infof("get token, data: %s", util.ToJSON(t))
+
+	return nil
+}
+
+func (t *OlaresSpace) Refresh(ctx context.Context) error {
+	if err := t.RefreshToken(ctx, false); err != nil {
+		return fmt.Errorf("get token error: %w", err)
+	}
+	logger.Infof("get token, data: %s", util.ToJSON(t))
+
+	return nil
+}
+
+func (t *OlaresSpace) ExpiresAt() (time.Time, bool) {
+	if t.OlaresSpaceSession == nil {
+		return time.Time{}, false
+	}
+	expire, err := t.OlaresSpaceSession.Expire()
+	if err != nil {
+		return time.Time{}, false
+	}
+	return expire, true
+}
+
+func (t *OlaresSpace) RepoUrl(name string) string {
 	// repoName = <name>_<uid>
-	var repoPrefix = filepath.Join(t.OlaresSpaceSession.Prefix, "restic", name)
-	var domain = fmt.Sprintf("s3.%s.amazonaws.com", t.OlaresSpaceSession.Region)
-	var repo = filepath.Join(domain, t.OlaresSpaceSession.Bucket, repoPrefix)
-	var repoUrl = fmt.Sprintf("s3:%s", repo)
+	var repoPrefix = path.Join(t.OlaresSpaceSession.Prefix, "restic", name)
+	var repo = path.Join(t.OlaresSpaceSession.Bucket, repoPrefix)
 
-	t.OlaresSpaceSession.RepoUrl = repoUrl
-	t.OlaresSpaceSession.Password = password
+	return fmt.Sprintf("s3:%s/%s", t.endpoint(), repo)
+}
+
+// endpoint returns the s3 compatible endpoint of the cloud the session was
+// issued for, CloudEndpoint overrides it.
+func (t *OlaresSpace) endpoint() string {
+	if t.CloudEndpoint != "" {
+		return strings.TrimRight(t.CloudEndpoint, "/")
+	}
+
+	var cloud = t.OlaresSpaceSession.Cloud
+	if cloud == "" {
+		cloud = t.parseCloudName()
+	}
+
+	var region = t.OlaresSpaceSession.Region
+	switch cloud {
+	case common.TencentCloudName:
+		return fmt.Sprintf("cos.%s.myqcloud.com", region)
+	case common.AliCloudName:
+		return fmt.Sprintf("oss-%s.aliyuncs.com", region)
+	default:
+		return fmt.Sprintf("s3.%s.amazonaws.com", region)
+	}
+}
+
+func (t *OlaresSpace) RepoEnv(name string) map[string]string {
+	return map[string]string{
+		"AWS_ACCESS_KEY_ID":     t.OlaresSpaceSession.Key,
+		"AWS_SECRET_ACCESS_KEY": t.OlaresSpaceSession.Secret.Value(),
+		"AWS_SESSION_TOKEN":     t.OlaresSpaceSession.Token.Value(),
+		"RESTIC_REPOSITORY":     t.RepoUrl(name),
+	}
+}
+
+func (t *OlaresSpace) SetRepoUrl(name, password string) {
+	t.OlaresSpaceSession.RepoUrl = t.RepoUrl(name)
+	t.OlaresSpaceSession.Password = util.Secret(password)
 }
 
 func (t *OlaresSpace) SetEnv() {
@@ -116,59 +194,51 @@ func (t *OlaresSpace) SetEnv() {
 	}
 
 	t.Env["AWS_ACCESS_KEY_ID"] = t.OlaresSpaceSession.Key
-	t.Env["AWS_SECRET_ACCESS_KEY"] = t.OlaresSpaceSession.Secret
-	t.Env["AWS_SESSION_TOKEN"] = t.OlaresSpaceSession.Token
+	t.Env["AWS_SECRET_ACCESS_KEY"] = t.OlaresSpaceSession.Secret.Value()
+	t.Env["AWS_SESSION_TOKEN"] = t.OlaresSpaceSession.Token.Value()
 	t.Env["RESTIC_REPOSITORY"] = t.OlaresSpaceSession.RepoUrl
-	t.Env["RESTIC_PASSWORD"] = t.OlaresSpaceSession.Password
-
-	msg := fmt.Sprintf("export AWS_ACCESS_KEY_ID=%s\nexport AWS_SECRET_ACCESS_KEY=%s\nexport AWS_SESSION_TOKEN=%s\nexport RESTIC_REPOSITORY=%s\nexport RESTIC_PASSWORD=%s\nexport AWS_REGION=%s\n",
-		t.OlaresSpaceSession.Key,
-		t.OlaresSpaceSession.Secret,
-		t.OlaresSpaceSession.Token,
-		t.OlaresSpaceSession.RepoUrl,
-		t.OlaresSpaceSession.Password,
-		t.OlaresSpaceSession.Region,
-	)
-	fmt.Println(msg)
-	logger.Debugf(msg)
+	t.Env["RESTIC_PASSWORD"] = t.OlaresSpaceSession.Password.Value()
+
+	logger.Debugf("olares space env: %s, AWS_REGION=%s", util.RedactEnv(t.Env), t.OlaresSpaceSession.Region)
 }
 
 func (t *OlaresSpace) GetEnv() map[string]string {
 	return t.Env
 }
 
-func (t *OlaresSpace) RefreshToken(isDebug bool) error {
+func (t *OlaresSpace) RefreshToken(ctx context.Context, isDebug bool) error {
 	if t.UserId != "" && t.UserToken != "" {
 		logger.Infof("retrieving olares space token, userid: %s, usertoken: %s", t.UserId, t.UserToken)
-		err := t.setToken(isDebug)
+		err := t.setToken(ctx, isDebug)
 		if err == nil {
 			return nil
 		}
 		logger.Info("failed to obtain olares space token, retrying, please wait...")
 	}
 
-	podIp, err := t.getPodIp()
+	podIp, err := t.getPodIp(ctx)
 	if err != nil {
 		return err
 	}
 
-	appKey, err := t.getAppKey()
+	appKey, err := t.getAppKey(ctx)
 	if err != nil {
 		return err
 	}
 
 	logger.Infof("retrieving user %s token", t.UserName)
-	userId, userToken, err := t.getUserToken(podIp, appKey)
+	userId, userToken, err := t.getUserToken(ctx, podIp, appKey)
 	if err != nil {
 		return err
 	}
 	t.UserId = userId
-	t.UserToken = userToken
+	t.UserToken = util.Secret(userToken)
+	logger.RegisterSecret(userToken)
 
-	return t.setToken(isDebug)
+	return t.setToken(ctx, isDebug)
 }
 
-func (t *OlaresSpace) SetAccount() error {
+func (t *OlaresSpace) SetAccount(ctx context.Context) error {
 	factory, err := client.NewFactory()
 	if err != nil {
 		return errors.WithStack(err)
@@ -188,12 +258,12 @@ func (t *OlaresSpace) SetAccount() error {
 
 	var accountName string
 	if err := retry.OnError(backoff, func(err error) bool {
-		return true
+		return ctx.Err() == nil
 	}, func() error {
-		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		getCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
 		defer cancel()
 
-		unstructuredUser, err := dynamicClient.Resource(UsersGVR).Get(ctx, t.UserName, metav1.GetOptions{})
+		unstructuredUser, err := dynamicClient.Resource(UsersGVR).Get(getCtx, t.UserName, metav1.GetOptions{})
 		if err != nil {
 			return errors.WithStack(err)
 		}
@@ -211,7 +281,7 @@ func (t *OlaresSpace) SetAccount() error {
 	return nil
 }
 
-func (t *OlaresSpace) getPodIp() (string, error) {
+func (t *OlaresSpace) getPodIp(ctx context.Context) (string, error) {
 	factory, err := client.NewFactory()
 	if err != nil {
 		return "", errors.WithStack(err)
@@ -222,7 +292,7 @@ func (t *OlaresSpace) getPodIp() (string, error) {
 		return "", errors.WithStack(err)
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
 	defer cancel()
 
 	pods, err := kubeClient.CoreV1().Pods(fmt.Sprintf("user-system-%s", t.UserName)).List(ctx, metav1.ListOptions{
@@ -245,7 +315,7 @@ func (t *OlaresSpace) getPodIp() (string, error) {
 	return podIp, nil
 }
 
-func (t *OlaresSpace) getAppKey() (string, error) {
+func (t *OlaresSpace) getAppKey(ctx context.Context) (string, error) {
 	factory, err := client.NewFactory()
 	if err != nil {
 		return "", errors.WithStack(err)
@@ -256,7 +326,7 @@ func (t *OlaresSpace) getAppKey() (string, error) {
 		return "", errors.WithStack(err)
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
 	defer cancel()
 
 	secret, err := kubeClient.CoreV1().Secrets("os-system").Get(ctx, "app-key", metav1.GetOptions{})
@@ -275,7 +345,7 @@ func (t *OlaresSpace) getAppKey() (string, error) {
 	return string(key), nil
 }
 
-func (t *OlaresSpace) getUserToken(podIp string, appKey string) (userid, token string, err error) {
+func (t *OlaresSpace) getUserToken(ctx context.Context, podIp string, appKey string) (userid, token string, err error) {
 	terminusNonce, err := util.GenTerminusNonce(appKey)
 	if err != nil {
 		logger.Errorf("generate nonce error: %v", err)
@@ -287,7 +357,7 @@ func (t *OlaresSpace) getUserToken(podIp string, appKey string) (userid, token s
 	var data = make(map[string]string)
 	data["name"] = fmt.Sprintf("integration-account:space:%s", t.AccountName)
 	logger.Infof("fetch account from settings: %s", settingsUrl)
-	resp, err := client.R().SetDebug(true).
+	resp, err := client.R().SetContext(ctx).
 		SetHeader(restful.HEADER_ContentType, restful.MIME_JSON).
 		SetHeader("Terminus-Nonce", terminusNonce).
 		SetBody(data).
@@ -295,6 +365,7 @@ func (t *OlaresSpace) getUserToken(podIp string, appKey string) (userid, token s
 		Post(settingsUrl)
 
 	if err != nil {
+		err = errors.WithStack(fmt.Errorf("%w: request settings account api error: %w", restic.ErrNetwork, err))
 		return
 	}
 
@@ -306,7 +377,7 @@ func (t *OlaresSpace) getUserToken(podIp string, appKey string) (userid, token s
 	accountResp := resp.Result().(*AccountResponse)
 
 	if accountResp.Code == 1 && accountResp.Message == "" {
-		err = errors.WithStack(fmt.Errorf("\nOlares Space is not enabled. Please go to the Settings - Integration page in the LarePass App to add Space\n"))
+		err = errors.WithStack(ErrSpaceNotEnabled)
 		return
 	} else if accountResp.Code != 0 {
 		err = errors.WithStack(fmt.Errorf("request settings account api response error, status: %d, message: %s", accountResp.Code, accountResp.Message))
@@ -329,11 +400,11 @@ func (t *OlaresSpace) getUserToken(podIp string, appKey string) (userid, token s
 	return
 }
 
-func (t *OlaresSpace) GetToken() {
-	t.setToken(false)
+func (t *OlaresSpace) GetToken(ctx context.Context) {
+	t.setToken(ctx, false)
 }
 
-func (t *OlaresSpace) setToken(isDebug bool) error {
+func (t *OlaresSpace) setToken(ctx context.Context, isDebug bool) error {
 	var backoff = wait.Backoff{
 		Duration: 3 * time.Second,
 		Factor:   2,
@@ -342,17 +413,17 @@ func (t *OlaresSpace) setToken(isDebug bool) error {
 	}
 
 	if err := retry.OnError(backoff, func(err error) bool {
-		return true
+		return ctx.Err() == nil
 	}, func() error {
 		var serverDomain = util.DefaultValue(common.DefaultCloudApiUrl, t.CloudApiMirror)
 
 		serverURL := fmt.Sprintf("%s/v1/resource/stsToken/backup", strings.TrimRight(serverDomain, "/"))
 
-		httpClient := resty.New().SetTimeout(15 * time.Second).SetDebug(true).SetTLSClientConfig(&tls.Config{InsecureSkipVerify: true})
-		resp, err := httpClient.R().
+		httpClient := resty.New().SetTimeout(15 * time.Second).SetTLSClientConfig(&tls.Config{InsecureSkipVerify: true})
+		resp, err := httpClient.R().SetContext(ctx).
 			SetFormData(map[string]string{
 				"userid":          t.UserId,
-				"token":           t.UserToken,
+				"token":           t.UserToken.Value(),
 				"cloudName":       t.parseCloudName(),
 				"region":          t.CloudRegion,
 				"clusterId":       util.MD5(t.UploadPath),
@@ -362,7 +433,7 @@ func (t *OlaresSpace) setToken(isDebug bool) error {
 			Post(serverURL)
 
 		if err != nil {
-			return errors.WithStack(fmt.Errorf("fetch data from cloud error: %v, url: %s", err, serverURL))
+			return errors.WithStack(fmt.Errorf("%w: fetch data from cloud error: %w, url: %s", restic.ErrNetwork, err, serverURL))
 		}
 
 		if resp.StatusCode() != http.StatusOK {
@@ -380,6 +451,7 @@ func (t *OlaresSpace) setToken(isDebug bool) error {
 		}
 
 		t.OlaresSpaceSession = queryResp.Data
+		logger.RegisterSecret(t.OlaresSpaceSession.Secret.Value(), t.OlaresSpaceSession.Token.Value())
 
 		if isDebug {
 		}
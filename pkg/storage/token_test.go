@@ -0,0 +1,58 @@
+package storage
+
+import (
+	"testing"
+
+	"bytetrade.io/web3os/uploader-sdk/pkg/common"
+)
+
+func TestOlaresSpaceRepoUrl(t *testing.T) {
+	var session = func(cloud, region string) *OlaresSpaceSession {
+		return &OlaresSpaceSession{
+			Cloud:  cloud,
+			Bucket: "terminus-us-west-1",
+			Prefix: "fbcf5f573ed242c28758-342957450633",
+			Region: region,
+		}
+	}
+
+	tests := []struct {
+		name        string
+		olaresSpace *OlaresSpace
+		want        string
+	}{
+		{
+			name:        "aws",
+			olaresSpace: &OlaresSpace{OlaresSpaceSession: session(common.AWSCloudName, "us-west-1")},
+			want:        "s3:s3.us-west-1.amazonaws.com/terminus-us-west-1/fbcf5f573ed242c28758-342957450633/restic/backup",
+		},
+		{
+			name:        "tencentcloud",
+			olaresSpace: &OlaresSpace{OlaresSpaceSession: session(common.TencentCloudName, "ap-beijing")},
+			want:        "s3:cos.ap-beijing.myqcloud.com/terminus-us-west-1/fbcf5f573ed242c28758-342957450633/restic/backup",
+		},
+		{
+			name:        "aliyuncloud",
+			olaresSpace: &OlaresSpace{OlaresSpaceSession: session(common.AliCloudName, "cn-hangzhou")},
+			want:        "s3:oss-cn-hangzhou.aliyuncs.com/terminus-us-west-1/fbcf5f573ed242c28758-342957450633/restic/backup",
+		},
+		{
+			name:        "session without cloud falls back to cloud name",
+			olaresSpace: &OlaresSpace{CloudName: common.TencentCloudName, OlaresSpaceSession: session("", "ap-singapore")},
+			want:        "s3:cos.ap-singapore.myqcloud.com/terminus-us-west-1/fbcf5f573ed242c28758-342957450633/restic/backup",
+		},
+		{
+			name:        "custom endpoint",
+			olaresSpace: &OlaresSpace{CloudEndpoint: "https://minio.local:9000/", OlaresSpaceSession: session(common.AWSCloudName, "us-west-1")},
+			want:        "s3:https://minio.local:9000/terminus-us-west-1/fbcf5f573ed242c28758-342957450633/restic/backup",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.olaresSpace.RepoUrl("backup"); got != tt.want {
+				t.Errorf("RepoUrl() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
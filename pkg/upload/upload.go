@@ -2,6 +2,8 @@ package upload
 
 import (
 	"context"
+	"io"
+	"time"
 
 	"bytetrade.io/web3os/uploader-sdk/pkg/restic"
 	"bytetrade.io/web3os/uploader-sdk/pkg/storage"
@@ -18,61 +20,134 @@ type Upload struct {
 	option Option
 }
 
+// Result describes the snapshot created by a successful upload.
+type Result struct {
+	SnapshotId          string        `json:"snapshot_id"`
+	FilesNew            uint          `json:"files_new"`
+	FilesChanged        uint          `json:"files_changed"`
+	FilesUnmodified     uint          `json:"files_unmodified"`
+	DataAdded           uint64        `json:"data_added"`
+	DataAddedPacked     uint64        `json:"data_added_packed"`
+	TotalFilesProcessed uint          `json:"total_files_processed"`
+	TotalBytesProcessed uint64        `json:"total_bytes_processed"`
+	Duration            time.Duration `json:"duration"`
+	RepoUrl             string        `json:"repo_url"`
+
+	// Incomplete is set when some files could not be read, the snapshot
+	// misses the files listed in FileErrors.
+	Incomplete bool                  `json:"incomplete,omitempty"`
+	FileErrors []*restic.ErrorUpdate `json:"file_errors,omitempty"`
+
+	// Forget is set when a retention policy was applied after the backup.
+	Forget *restic.ForgetReport `json:"forget,omitempty"`
+}
+
 type Option struct {
 	Name                 string
 	UserName             string
 	Password             string
+	PasswordProvider     storage.PasswordProvider
 	CloudName            string
 	CloudRegion          string
 	UploadPath           string
 	CloudApiMirror       string
+	CloudEndpoint        string
 	LimitUploadRate      string
 	StorageTokenDuration string
+	Timeout              time.Duration
+	Progress             restic.ProgressFunc
+	Backend              storage.Backend
+	Retention            *restic.RetentionPolicy
+	ErrorPolicy          restic.ErrorPolicy
+	BackupOptions        *restic.BackupOptions
+	// Stdin, when set, is backed up as the file StdinFilename instead of
+	// UploadPath, and StdinCommand does the same with the output of a
+	// command.
+	Stdin         io.Reader
+	StdinFilename string
+	StdinCommand  []string
 }
 
-func (u *Upload) Upload(opt Option) error {
-	ctx, cancel := context.WithCancel(context.TODO())
-	defer cancel()
-
+// Upload runs the backup. A snapshot that misses files under
+// restic.ErrorPolicyFail is returned along with restic.ErrIncompleteSnapshot.
+func (u *Upload) Upload(ctx context.Context, opt Option) (*Result, error) {
 	u.option = opt
 
+	var cancel context.CancelFunc
+	if u.option.Timeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, u.option.Timeout)
+	} else {
+		ctx, cancel = context.WithCancel(ctx)
+	}
+	defer cancel()
+
 	var storageClient = &storage.StorageClient{
 		Name:                 u.option.Name,
 		UserName:             u.option.UserName,
 		Password:             u.option.Password,
+		PasswordProvider:     u.option.PasswordProvider,
 		CloudName:            u.option.CloudName,
 		CloudRegion:          u.option.CloudRegion,
 		UploadPath:           u.option.UploadPath,
 		CloudApiMirror:       u.option.CloudApiMirror,
+		CloudEndpoint:        u.option.CloudEndpoint,
 		LimitUploadRate:      u.option.LimitUploadRate,
 		StorageTokenDuration: u.option.StorageTokenDuration,
+		Progress:             u.option.Progress,
+		Backend:              u.option.Backend,
+		Retention:            u.option.Retention,
+		ErrorPolicy:          u.option.ErrorPolicy,
+		BackupOptions:        u.option.BackupOptions,
+		Stdin:                u.option.Stdin,
+		StdinFilename:        u.option.StdinFilename,
+		StdinCommand:         u.option.StdinCommand,
 	}
 
 	var (
-		err     error
-		exitCh  = make(chan *storage.StorageResponse)
-		summary *restic.SummaryOutput
+		exitCh = make(chan *storage.StorageResponse, 1)
+		e      *storage.StorageResponse
 	)
 
 	go storageClient.UploadToStorage(ctx, exitCh)
 
 	select {
-	case e, ok := <-exitCh:
-		if ok && e.Error != nil {
-			err = e.Error
-		}
-		summary = e.Summary
+	case e = <-exitCh:
 	case <-ctx.Done():
-		err = errors.Errorf("backup %q osdata timed out in 2 hour", u.option.Name)
+		// restic is interrupted, wait until it exited so that nothing reads
+		// the stdin of the caller once Upload returned
+		e = <-exitCh
+		if e.Summary == nil {
+			if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+				return nil, errors.Wrapf(ctx.Err(), "backup %q osdata timed out", u.option.Name)
+			}
+			return nil, errors.Wrapf(ctx.Err(), "backup %q osdata canceled", u.option.Name)
+		}
 	}
 
-	if err != nil {
-		return err
-	}
+	var summary, forget, repoUrl = e.Summary, e.Forget, e.RepoUrl
 
-	if summary != nil {
-		logger.Infof("upload successful, data: %s", util.ToJSON(summary))
+	if summary == nil {
+		if e.Error != nil {
+			return nil, e.Error
+		}
+		return nil, errors.Errorf("backup %q osdata finished without summary", u.option.Name)
 	}
 
-	return nil
+	logger.Infof("upload successful, data: %s", util.ToJSON(summary))
+
+	return &Result{
+		SnapshotId:          summary.SnapshotID,
+		FilesNew:            summary.FilesNew,
+		FilesChanged:        summary.FilesChanged,
+		FilesUnmodified:     summary.FilesUnmodified,
+		DataAdded:           summary.DataAdded,
+		DataAddedPacked:     summary.DataAddedPacked,
+		TotalFilesProcessed: summary.TotalFilesProcessed,
+		TotalBytesProcessed: summary.TotalBytesProcessed,
+		Duration:            time.Duration(summary.TotalDuration * float64(time.Second)),
+		RepoUrl:             repoUrl,
+		Incomplete:          summary.Incomplete,
+		FileErrors:          summary.FileErrors,
+		Forget:              forget,
+	}, e.Error
 }
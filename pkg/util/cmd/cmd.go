@@ -4,19 +4,43 @@ import (
 	"bufio"
 	"context"
 	"fmt"
+	"io"
 	"os"
 	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
 
+	"bytetrade.io/web3os/uploader-sdk/pkg/util"
 	"bytetrade.io/web3os/uploader-sdk/pkg/util/logger"
 	"github.com/pkg/errors"
 )
 
+const (
+	// maxLineSize bounds a single output line, restic status lines carry the
+	// list of files in progress and restic find prints all its matches on a
+	// single line, both may exceed the bufio default.
+	maxLineSize = 64 * 1024 * 1024
+	// stderrTailLines and stderrTailBytes bound the stderr kept for ExitError.
+	stderrTailLines = 20
+	stderrTailBytes = 4096
+	// defaultGracePeriod is how long a canceled command may take to exit
+	// after SIGINT before it is killed.
+	defaultGracePeriod = 10 * time.Second
+)
+
 type Command struct {
 	options CommandOptions
 	ctx     context.Context
 	cancel  context.CancelFunc
 	cmd     *exec.Cmd
-	Ch      chan []byte
+	exited  chan struct{}
+	// Ch receives the output lines and is closed when the command ends. With
+	// SplitStderr the stderr lines go to ErrCh instead, which must be drained
+	// as well, ErrCh is nil otherwise.
+	Ch    chan []byte
+	ErrCh chan []byte
 }
 
 type CommandOptions struct {
@@ -24,16 +48,43 @@ type CommandOptions struct {
 	Args  []string
 	Envs  map[string]string
 	Print bool
+	// Stdin is the standard input of the command, empty when nil.
+	Stdin io.Reader
+	// GracePeriod is how long the command may take to exit after it is
+	// interrupted on cancellation, defaults to 10 seconds. restic uses it to
+	// remove its repository lock.
+	GracePeriod time.Duration
+	// SplitStderr sends the stderr lines to ErrCh rather than to Ch.
+	SplitStderr bool
+}
+
+// ExitError is returned by Run when the command exits with a non-zero code.
+type ExitError struct {
+	Command  string
+	ExitCode int
+	// Stderr holds the last lines the command printed to stderr.
+	Stderr string
+}
+
+func (e *ExitError) Error() string {
+	if e.Stderr == "" {
+		return fmt.Sprintf("%s exited with code %d", e.Command, e.ExitCode)
+	}
+	return fmt.Sprintf("%s exited with code %d: %s", e.Command, e.ExitCode, e.Stderr)
 }
 
 func NewCommand(ctx context.Context, opts CommandOptions) *Command {
-	var cmdCtx, cancel = context.WithCancel(context.Background())
-	return &Command{
+	var cmdCtx, cancel = context.WithCancel(ctx)
+	var c = &Command{
 		options: opts,
 		ctx:     cmdCtx,
 		cancel:  cancel,
 		Ch:      make(chan []byte, 50),
 	}
+	if opts.SplitStderr {
+		c.ErrCh = make(chan []byte, 50)
+	}
+	return c
 }
 
 func (c *Command) Cancel() {
@@ -44,10 +95,105 @@ func (c *Command) GetCmd() *exec.Cmd {
 	return c.cmd
 }
 
+// Run starts the command and streams its output to Ch, and to ErrCh with
+// SplitStderr, until it exits. It returns an *ExitError when the command fails and the context
+// error when the command was canceled.
+//
+// On cancellation the process group of the command is interrupted, and
+// killed when it is still running after the grace period.
 func (c *Command) Run() (string, error) {
-	var result string
-	var err error
+	stdout, stderr, err := c.start()
+	if err != nil {
+		c.closeChannels()
+		return "", err
+	}
+
+	var errCh = c.Ch
+	if c.ErrCh != nil {
+		errCh = c.ErrCh
+	}
+	var tail = new(tailBuffer)
+	var wg sync.WaitGroup
+	var stdoutErr, stderrErr error
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		stdoutErr = c.scan(stdout, c.Ch, nil)
+	}()
+	go func() {
+		defer wg.Done()
+		stderrErr = c.scan(stderr, errCh, tail)
+	}()
+	wg.Wait()
+	c.closeChannels()
+
+	if err := c.wait(tail); err != nil {
+		return "", err
+	}
+
+	if stdoutErr != nil {
+		return "", errors.Wrap(stdoutErr, "stdout scanner error")
+	}
+	if stderrErr != nil {
+		return "", errors.Wrap(stderrErr, "stderr scanner error")
+	}
+
+	return "", nil
+}
+
+// Stream starts the command and returns its raw stdout, for commands that
+// print binary data. Ch and ErrCh are not used, the stderr lines are only
+// kept for the *ExitError that Read returns in place of io.EOF when the
+// command fails. Close stops the command when it is still running.
+func (c *Command) Stream() (io.ReadCloser, error) {
+	c.closeChannels()
+
+	stdout, stderr, err := c.start()
+	if err != nil {
+		return nil, err
+	}
+
+	var s = &stream{
+		c:          c,
+		stdout:     stdout,
+		tail:       new(tailBuffer),
+		stderrDone: make(chan struct{}),
+	}
+	go func() {
+		defer close(s.stderrDone)
+		if err := c.scan(stderr, nil, s.tail); err != nil {
+			logger.Debugf("[Cmd] stderr scanner error: %v", err)
+		}
+	}()
+
+	return s, nil
+}
+
+// start starts the command in its own process group and returns its output
+// pipes.
+func (c *Command) start() (io.ReadCloser, io.ReadCloser, error) {
+	c.exited = make(chan struct{})
 	c.cmd = exec.CommandContext(c.ctx, c.options.Path, c.options.Args...)
+	if c.options.Stdin != nil {
+		c.cmd.Stdin = c.options.Stdin
+		// do not wait for a stdin reader that blocks after the exit
+		c.cmd.WaitDelay = c.gracePeriod()
+	}
+	setProcessGroup(c.cmd)
+	c.cmd.Cancel = func() error {
+		logger.Infof("[Cmd] interrupt %s", c.cmd.String())
+		go func() {
+			select {
+			case <-c.exited:
+			case <-time.After(c.gracePeriod()):
+				logger.Warnf("[Cmd] %s did not exit in %s, kill it", c.cmd.String(), c.gracePeriod())
+				if err := killProcess(c.cmd); err != nil {
+					logger.Debugf("[Cmd] kill error: %v", err)
+				}
+			}
+		}()
+		return interruptProcess(c.cmd)
+	}
 	c.cmd.Env = append(os.Environ(), c.cmd.Env...)
 
 	for k, v := range c.options.Envs {
@@ -56,37 +202,151 @@ func (c *Command) Run() (string, error) {
 
 	stdout, err := c.cmd.StdoutPipe()
 	if err != nil {
-		return "", errors.Wrap(err, "stdout pipe error")
+		return nil, nil, errors.Wrap(err, "stdout pipe error")
+	}
+	stderr, err := c.cmd.StderrPipe()
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "stderr pipe error")
 	}
-	c.cmd.Stderr = c.cmd.Stdout
 
 	logger.Infof("[Cmd] %s", c.cmd.String())
+	logger.Debugf("[Cmd] env: %s", util.RedactEnv(c.options.Envs))
 	if err := c.cmd.Start(); err != nil {
-		return "", errors.Wrap(err, "cmd start error")
+		return nil, nil, errors.Wrap(err, "cmd start error")
 	}
 
-	defer func() (string, error) {
-		close(c.Ch)
-		if errWait := c.cmd.Wait(); errWait != nil {
-			return "", errors.Wrapf(errWait, fmt.Sprintf("wait error for command: %s, exec error %v", c.cmd.String(), err))
+	return stdout, stderr, nil
+}
+
+// wait waits for the command to exit once its output is read, and turns the
+// result into the error Run returns.
+func (c *Command) wait(tail *tailBuffer) error {
+	var waitErr = c.cmd.Wait()
+	close(c.exited)
+
+	if ctxErr := c.ctx.Err(); ctxErr != nil {
+		return ctxErr
+	}
+
+	var exitErr *exec.ExitError
+	if errors.As(waitErr, &exitErr) {
+		return &ExitError{
+			Command:  filepath.Base(c.options.Path),
+			ExitCode: exitErr.ExitCode(),
+			Stderr:   tail.String(),
 		}
-		return result, err
-	}()
+	}
+	if waitErr != nil {
+		return errors.Wrapf(waitErr, "wait error for command: %s", c.cmd.String())
+	}
+
+	return nil
+}
 
-	scanner := bufio.NewScanner(stdout)
+func (c *Command) closeChannels() {
+	close(c.Ch)
+	if c.ErrCh != nil {
+		close(c.ErrCh)
+	}
+}
+
+func (c *Command) gracePeriod() time.Duration {
+	if c.options.GracePeriod > 0 {
+		return c.options.GracePeriod
+	}
+	return defaultGracePeriod
+}
+
+// scan sends every line of r to ch when ch is not nil and keeps it in tail
+// when tail is not nil. Once the command is canceled the lines are read but dropped, so the
+// process never blocks on a full pipe.
+func (c *Command) scan(r io.Reader, ch chan<- []byte, tail *tailBuffer) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, bufio.MaxScanTokenSize), maxLineSize)
 	for scanner.Scan() {
+		if c.ctx.Err() != nil {
+			continue
+		}
+		line := make([]byte, len(scanner.Bytes()))
+		copy(line, scanner.Bytes())
+		if tail != nil {
+			tail.add(string(line))
+		}
+		if ch == nil {
+			continue
+		}
 		select {
+		case ch <- line:
 		case <-c.ctx.Done():
-			return result, nil
-		default:
-			line := scanner.Bytes()
-			c.Ch <- line
 		}
 	}
 
-	if err := scanner.Err(); err != nil {
-		return result, errors.Wrap(err, "scanner error")
+	return scanner.Err()
+}
+
+// tailBuffer keeps the last lines written to it within stderrTailLines and
+// stderrTailBytes.
+type tailBuffer struct {
+	lines []string
+	size  int
+}
+
+func (t *tailBuffer) add(line string) {
+	line = strings.TrimSpace(line)
+	if line == "" {
+		return
+	}
+	if len(line) > stderrTailBytes {
+		line = line[len(line)-stderrTailBytes:]
+	}
+
+	t.lines = append(t.lines, line)
+	t.size += len(line)
+	for len(t.lines) > stderrTailLines || t.size > stderrTailBytes {
+		t.size -= len(t.lines[0])
+		t.lines = t.lines[1:]
+	}
+}
+
+func (t *tailBuffer) String() string {
+	return strings.Join(t.lines, "\n")
+}
+
+// stream is the stdout of a command started by Stream.
+type stream struct {
+	c          *Command
+	stdout     io.ReadCloser
+	tail       *tailBuffer
+	stderrDone chan struct{}
+
+	once sync.Once
+	err  error
+}
+
+func (s *stream) Read(p []byte) (int, error) {
+	n, err := s.stdout.Read(p)
+	if err == io.EOF {
+		if waitErr := s.wait(); waitErr != nil {
+			return n, waitErr
+		}
 	}
+	return n, err
+}
+
+// Close stops the command when it is still running, a command stopped this
+// way is not reported as an error.
+func (s *stream) Close() error {
+	s.c.cancel()
+	if err := s.wait(); !errors.Is(err, context.Canceled) {
+		return err
+	}
+	return nil
+}
 
-	return result, nil
+func (s *stream) wait() error {
+	s.once.Do(func() {
+		<-s.stderrDone
+		s.err = s.c.wait(s.tail)
+	})
+	return s.err
 }
@@ -0,0 +1,197 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"testing"
+	"time"
+
+	"bytetrade.io/web3os/uploader-sdk/pkg/util/logger"
+	"go.uber.org/zap"
+)
+
+func init() {
+	logger.SetLogger(zap.NewNop().Sugar())
+}
+
+func runScript(ctx context.Context, script string) (stdout, stderr []string, err error) {
+	c := NewCommand(ctx, CommandOptions{Path: "/bin/sh", Args: []string{"-c", script}, SplitStderr: true})
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		for line := range c.Ch {
+			stdout = append(stdout, string(line))
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for line := range c.ErrCh {
+			stderr = append(stderr, string(line))
+		}
+	}()
+
+	_, err = c.Run()
+	wg.Wait()
+	return
+}
+
+func TestRunSeparatesStreams(t *testing.T) {
+	stdout, stderr, err := runScript(context.Background(), "echo out; echo err >&2")
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if len(stdout) != 1 || stdout[0] != "out" {
+		t.Errorf("stdout = %q, want [out]", stdout)
+	}
+	if len(stderr) != 1 || stderr[0] != "err" {
+		t.Errorf("stderr = %q, want [err]", stderr)
+	}
+}
+
+func TestRunMergesStreams(t *testing.T) {
+	c := NewCommand(context.Background(), CommandOptions{Path: "/bin/sh", Args: []string{"-c", "for i in $(seq 1 100); do echo err$i >&2; done; echo out"}})
+	if c.ErrCh != nil {
+		t.Fatalf("ErrCh is set without SplitStderr")
+	}
+
+	var lines int
+	var done = make(chan struct{})
+	go func() {
+		defer close(done)
+		for range c.Ch {
+			lines++
+		}
+	}()
+
+	if _, err := c.Run(); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	<-done
+	if lines != 101 {
+		t.Errorf("Ch received %d lines, want 101", lines)
+	}
+}
+
+func TestRunExitError(t *testing.T) {
+	_, _, err := runScript(context.Background(), "for i in $(seq 1 30); do echo line$i >&2; done; exit 3")
+
+	var exitErr *ExitError
+	if !errors.As(err, &exitErr) {
+		t.Fatalf("Run() error = %v, want *ExitError", err)
+	}
+	if exitErr.ExitCode != 3 {
+		t.Errorf("ExitCode = %d, want 3", exitErr.ExitCode)
+	}
+
+	var tail = new(tailBuffer)
+	for i := 11; i <= 30; i++ {
+		tail.add(fmt.Sprintf("line%d", i))
+	}
+	if exitErr.Stderr != tail.String() {
+		t.Errorf("Stderr = %q, want the last %d lines", exitErr.Stderr, stderrTailLines)
+	}
+}
+
+func TestRunCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(100*time.Millisecond, cancel)
+
+	_, _, err := runScript(ctx, "echo start; exec sleep 10")
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("Run() error = %v, want context.Canceled", err)
+	}
+}
+
+func TestTailBufferBytes(t *testing.T) {
+	var tail = new(tailBuffer)
+	var long = make([]byte, stderrTailBytes+10)
+	for i := range long {
+		long[i] = 'a'
+	}
+	tail.add("first")
+	tail.add(string(long))
+
+	if got := len(tail.String()); got != stderrTailBytes {
+		t.Errorf("len(tail) = %d, want %d", got, stderrTailBytes)
+	}
+}
+
+func TestRunKillsProcessGroup(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(100*time.Millisecond, cancel)
+
+	// the background sleep ignores SIGINT and keeps the pipes open until
+	// the process group is killed
+	c := NewCommand(ctx, CommandOptions{
+		Path:        "/bin/sh",
+		Args:        []string{"-c", "sleep 10 & wait"},
+		GracePeriod: 200 * time.Millisecond,
+	})
+	go func() {
+		for range c.Ch {
+		}
+	}()
+
+	var start = time.Now()
+	if _, err := c.Run(); !errors.Is(err, context.Canceled) {
+		t.Fatalf("Run() error = %v, want context.Canceled", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("Run() returned after %s, want the process group killed after the grace period", elapsed)
+	}
+}
+
+func TestStream(t *testing.T) {
+	c := NewCommand(context.Background(), CommandOptions{Path: "/bin/sh", Args: []string{"-c", `printf 'a\000b'`}})
+	rc, err := c.Stream()
+	if err != nil {
+		t.Fatalf("Stream() error = %v", err)
+	}
+	defer rc.Close()
+
+	data, err := io.ReadAll(rc)
+	if err != nil {
+		t.Fatalf("ReadAll() error = %v", err)
+	}
+	if string(data) != "a\x00b" {
+		t.Errorf("stdout = %q, want %q", data, "a\x00b")
+	}
+}
+
+func TestStreamExitError(t *testing.T) {
+	c := NewCommand(context.Background(), CommandOptions{Path: "/bin/sh", Args: []string{"-c", "echo partial; echo failed >&2; exit 1"}})
+	rc, err := c.Stream()
+	if err != nil {
+		t.Fatalf("Stream() error = %v", err)
+	}
+	defer rc.Close()
+
+	_, err = io.ReadAll(rc)
+	var exitErr *ExitError
+	if !errors.As(err, &exitErr) {
+		t.Fatalf("ReadAll() error = %v, want *ExitError", err)
+	}
+	if exitErr.ExitCode != 1 || exitErr.Stderr != "failed" {
+		t.Errorf("ExitError = %+v, want code 1 and stderr %q", exitErr, "failed")
+	}
+}
+
+func TestStreamClose(t *testing.T) {
+	c := NewCommand(context.Background(), CommandOptions{Path: "/bin/sh", Args: []string{"-c", "exec yes"}})
+	rc, err := c.Stream()
+	if err != nil {
+		t.Fatalf("Stream() error = %v", err)
+	}
+
+	if _, err := io.ReadFull(rc, make([]byte, 16)); err != nil {
+		t.Fatalf("ReadFull() error = %v", err)
+	}
+	if err := rc.Close(); err != nil {
+		t.Errorf("Close() error = %v", err)
+	}
+}
@@ -46,6 +46,14 @@ func InitLog(jsonLogDir, consoleLogFilePath string, consoleLogTruncate bool) {
 	if err != nil {
 		panic(err)
 	}
+	core := newCore(zapcore.Lock(os.Stdout), zapcore.AddSync(consoleLogFile), zapcore.AddSync(jsonLogFile))
+	logger = zap.New(core, zap.AddCaller(), zap.AddCallerSkip(1), zap.AddStacktrace(zapcore.FatalLevel)).Sugar()
+}
+
+// newCore tees the entries to the console, the console log file and the json
+// log file. Each core is wrapped for redaction on its own, a tee writes an
+// entry to all its cores once one of them accepts its level.
+func newCore(console, consoleLogFile, jsonLogFile zapcore.WriteSyncer) zapcore.Core {
 	consolePriority := zap.LevelEnablerFunc(func(lvl zapcore.Level) bool {
 		return lvl > zapcore.DebugLevel
 	})
@@ -78,18 +86,18 @@ func InitLog(jsonLogDir, consoleLogFilePath string, consoleLogTruncate bool) {
 		EncodeCaller:   zapcore.ShortCallerEncoder,
 	}
 
-	consoleDebugging := zapcore.Lock(os.Stdout)
-
-	core := zapcore.NewTee(
-		zapcore.NewCore(zapcore.NewConsoleEncoder(consoleEncoderConfig), consoleDebugging, consolePriority),
-		zapcore.NewCore(zapcore.NewConsoleEncoder(consoleEncoderConfig), zapcore.AddSync(consoleLogFile), consolePriority),
-		zapcore.NewCore(zapcore.NewJSONEncoder(fileEncoder), zapcore.AddSync(jsonLogFile), jsonLogFilePriority),
+	return zapcore.NewTee(
+		newRedactCore(zapcore.NewCore(zapcore.NewConsoleEncoder(consoleEncoderConfig), console, consolePriority)),
+		newRedactCore(zapcore.NewCore(zapcore.NewConsoleEncoder(consoleEncoderConfig), consoleLogFile, consolePriority)),
+		newRedactCore(zapcore.NewCore(zapcore.NewJSONEncoder(fileEncoder), jsonLogFile, jsonLogFilePriority)),
 	)
-	logger = zap.New(core, zap.AddCaller(), zap.AddCallerSkip(1), zap.AddStacktrace(zapcore.FatalLevel)).Sugar()
 }
 
+// SetLogger replaces the logger of the SDK. Its core is wrapped for redaction
+// as a whole, so a core that tees to cores of different levels writes every
+// entry one of them accepts to all of them.
 func SetLogger(l *zap.SugaredLogger) {
-	logger = l
+	logger = l.Desugar().WithOptions(zap.WrapCore(newRedactCore)).Sugar()
 }
 
 func isDirExist(path string) (bool, error) {
@@ -0,0 +1,40 @@
+package logger
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+func TestCoreLevels(t *testing.T) {
+	var console, consoleLogFile, jsonLogFile bytes.Buffer
+	var l = zap.New(newCore(zapcore.AddSync(&console), zapcore.AddSync(&consoleLogFile), zapcore.AddSync(&jsonLogFile))).Sugar()
+
+	RegisterSecret("hunter22", "pw1")
+	l.Debugf("debug line")
+	l.Infof("info line, password hunter22, key pw1, copied 3pw1 files")
+
+	for name, out := range map[string]string{"console": console.String(), "console log file": consoleLogFile.String()} {
+		if strings.Contains(out, "debug line") {
+			t.Errorf("%s = %q, want no debug entries", name, out)
+		}
+		if !strings.Contains(out, "info line") {
+			t.Errorf("%s = %q, want the info entry", name, out)
+		}
+	}
+	if !strings.Contains(jsonLogFile.String(), "debug line") {
+		t.Errorf("json log file = %q, want the debug entry", jsonLogFile.String())
+	}
+
+	for _, out := range []string{console.String(), consoleLogFile.String(), jsonLogFile.String()} {
+		if strings.Contains(out, "hunter22") || strings.Contains(out, "key pw1") {
+			t.Errorf("log = %q, want the secrets masked", out)
+		}
+		if !strings.Contains(out, "3pw1 files") {
+			t.Errorf("log = %q, want short secrets inside other words kept", out)
+		}
+	}
+}
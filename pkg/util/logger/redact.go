@@ -0,0 +1,118 @@
+package logger
+
+import (
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+
+	"bytetrade.io/web3os/uploader-sdk/pkg/util"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+var secrets sync.Map
+
+// minSecretLength is the length from which secrets are masked inside other
+// words too, shorter ones would garble ordinary words and numbers.
+const minSecretLength = 6
+
+// RegisterSecret masks every later occurrence of value in log messages and
+// string fields, whatever the format string or field name it is logged with.
+// Values shorter than minSecretLength are masked where they stand as a whole
+// token only.
+func RegisterSecret(values ...string) {
+	for _, v := range values {
+		if v == "" {
+			continue
+		}
+		secrets.Store(v, struct{}{})
+	}
+}
+
+func redactString(s string) string {
+	secrets.Range(func(key, _ any) bool {
+		if v := key.(string); len(v) >= minSecretLength {
+			s = strings.ReplaceAll(s, v, util.SecretMask)
+		} else {
+			s = replaceToken(s, v, util.SecretMask)
+		}
+		return true
+	})
+	return s
+}
+
+// replaceToken replaces the occurrences of old in s that are neither
+// preceded nor followed by a letter or digit.
+func replaceToken(s, old, new string) string {
+	var b strings.Builder
+	var from = 0
+	for {
+		i := strings.Index(s[from:], old)
+		if i < 0 {
+			break
+		}
+		var start = from + i
+		var end = start + len(old)
+		before, _ := utf8.DecodeLastRuneInString(s[:start])
+		after, _ := utf8.DecodeRuneInString(s[end:])
+		b.WriteString(s[from:start])
+		if (start > 0 && isWordRune(before)) || (end < len(s) && isWordRune(after)) {
+			b.WriteString(old)
+		} else {
+			b.WriteString(new)
+		}
+		from = end
+	}
+	b.WriteString(s[from:])
+	return b.String()
+}
+
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+func redactFields(fields []zapcore.Field) []zapcore.Field {
+	var res = make([]zapcore.Field, len(fields))
+	for i, f := range fields {
+		switch {
+		case util.IsSensitiveKey(f.Key):
+			res[i] = zap.String(f.Key, util.SecretMask)
+		case f.Type == zapcore.StringType:
+			res[i] = zap.String(f.Key, redactString(f.String))
+		default:
+			res[i] = f
+		}
+	}
+	return res
+}
+
+// redactCore drops credentials from entries before they reach the wrapped
+// core. It writes every entry the wrapped core accepts to the whole of it, so
+// it wraps the cores of a tee one by one rather than the tee.
+type redactCore struct {
+	zapcore.Core
+}
+
+func newRedactCore(core zapcore.Core) zapcore.Core {
+	if _, ok := core.(*redactCore); ok {
+		return core
+	}
+	return &redactCore{Core: core}
+}
+
+func (c *redactCore) With(fields []zapcore.Field) zapcore.Core {
+	return &redactCore{Core: c.Core.With(redactFields(fields))}
+}
+
+func (c *redactCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Core.Check(ent, nil) != nil {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+func (c *redactCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	ent.Message = redactString(ent.Message)
+	return c.Core.Write(ent, redactFields(fields))
+}
@@ -0,0 +1,69 @@
+package util
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+const SecretMask = "******"
+
+// Secret holds a credential. It prints and marshals as a mask so that
+// structs carrying it can be logged, Value returns the real content.
+type Secret string
+
+func (s Secret) Value() string {
+	return string(s)
+}
+
+func (s Secret) String() string {
+	if s == "" {
+		return ""
+	}
+	return SecretMask
+}
+
+func (s Secret) GoString() string {
+	return fmt.Sprintf("%q", s.String())
+}
+
+func (s Secret) MarshalJSON() ([]byte, error) {
+	return json.Marshal(s.String())
+}
+
+func (s *Secret) UnmarshalJSON(data []byte) error {
+	var v string
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	*s = Secret(v)
+	return nil
+}
+
+// IsSensitiveKey reports whether a field or environment variable named key
+// is expected to carry a credential.
+func IsSensitiveKey(key string) bool {
+	key = strings.ToLower(key)
+	for _, word := range []string{"password", "secret", "token", "credential", "access_key"} {
+		if strings.Contains(key, word) {
+			return true
+		}
+	}
+	return false
+}
+
+// RedactEnv formats envs as KEY=VALUE pairs with the values of sensitive
+// keys masked.
+func RedactEnv(envs map[string]string) string {
+	var pairs = make([]string, 0, len(envs))
+	for k, v := range envs {
+		if IsSensitiveKey(k) && v != "" {
+			v = SecretMask
+		}
+		pairs = append(pairs, fmt.Sprintf("%s=%s", k, v))
+	}
+	sort.Strings(pairs)
+
+	return strings.Join(pairs, " ")
+}
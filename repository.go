@@ -0,0 +1,102 @@
+package uploadersdk
+
+import (
+	"context"
+
+	"bytetrade.io/web3os/uploader-sdk/pkg/restic"
+	"bytetrade.io/web3os/uploader-sdk/pkg/storage"
+	"go.uber.org/zap"
+)
+
+type CheckOptions = restic.CheckOptions
+
+type CheckReport = restic.CheckReport
+
+type StatsMode = restic.StatsMode
+
+type Stats = restic.Stats
+
+type RepoUsage = restic.RepoUsage
+
+type Key = restic.Key
+
+const (
+	StatsRestoreSize     = restic.StatsRestoreSize
+	StatsFilesByContents = restic.StatsFilesByContents
+	StatsRawData         = restic.StatsRawData
+)
+
+type RepositoryClient struct {
+	storage *storage.StorageClient
+}
+
+type RepositoryClientOption struct {
+	Name                 string
+	UserName             string
+	Password             string
+	PasswordProvider     PasswordProvider
+	CloudName            string
+	CloudRegion          string
+	CloudApiMirror       string
+	CloudEndpoint        string
+	StorageTokenDuration string
+	Backend              Backend
+	BaseDir              string
+	Version              string
+	Logger               *zap.SugaredLogger
+}
+
+func NewRepositoryClient(opt *RepositoryClientOption) *RepositoryClient {
+	var client = &RepositoryClient{
+		storage: &storage.StorageClient{
+			Name:                 opt.Name,
+			UserName:             opt.UserName,
+			Password:             opt.Password,
+			PasswordProvider:     opt.PasswordProvider,
+			CloudName:            opt.CloudName,
+			CloudRegion:          opt.CloudRegion,
+			CloudApiMirror:       opt.CloudApiMirror,
+			CloudEndpoint:        opt.CloudEndpoint,
+			StorageTokenDuration: opt.StorageTokenDuration,
+			Backend:              opt.Backend,
+		},
+	}
+
+	setLogger(opt.BaseDir, opt.Version, "backup_repository.log", opt.Logger)
+
+	return client
+}
+
+// Check verifies the structure of the named repository and, depending on
+// opts, the content of its pack files. Problems found are reported in
+// CheckReport, the error is only set when the check could not run.
+func (c *RepositoryClient) Check(ctx context.Context, opts *CheckOptions) (*CheckReport, error) {
+	return c.storage.Check(ctx, opts)
+}
+
+// Stats counts the snapshots of the named repository in mode, only the given
+// snapshots when snapshotIds is not empty.
+func (c *RepositoryClient) Stats(ctx context.Context, mode StatsMode, snapshotIds ...string) (*Stats, error) {
+	return c.storage.Stats(ctx, mode, snapshotIds...)
+}
+
+// RepoUsage reports the storage the snapshots of the named repository
+// consume. StoredSize is a lower bound of the Olares Space quota used, run
+// SnapshotClient.Prune to release the data of forgotten snapshots.
+func (c *RepositoryClient) RepoUsage(ctx context.Context) (*RepoUsage, error) {
+	return c.storage.RepoUsage(ctx)
+}
+
+// ListKeys returns the keys of the named repository, Current marks the key
+// of the configured password.
+func (c *RepositoryClient) ListKeys(ctx context.Context) ([]*Key, error) {
+	return c.storage.ListKeys(ctx)
+}
+
+// RotatePassword changes the password of the named repository. A key of
+// newPassword is added and verified before the key of the current password
+// is removed, so the repository stays accessible when the rotation fails.
+// The client uses newPassword afterwards.
+func (c *RepositoryClient) RotatePassword(ctx context.Context, newPassword string) error {
+	return c.storage.RotatePassword(ctx, newPassword)
+}
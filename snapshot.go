@@ -0,0 +1,150 @@
+package uploadersdk
+
+import (
+	"context"
+	"io"
+
+	"bytetrade.io/web3os/uploader-sdk/pkg/restic"
+	"bytetrade.io/web3os/uploader-sdk/pkg/storage"
+	"go.uber.org/zap"
+)
+
+type Snapshot = restic.Snapshot
+
+type SnapshotFilter = restic.SnapshotFilter
+
+type Node = restic.Node
+
+type FindFilter = restic.FindFilter
+
+type FindMatch = restic.FindMatch
+
+type ArchiveFormat = restic.ArchiveFormat
+
+type DiffReport = restic.DiffReport
+
+type DiffChange = restic.DiffChange
+
+type DiffStats = restic.DiffStats
+
+type CopyReport = restic.CopyReport
+
+const (
+	ArchiveTar = restic.ArchiveTar
+	ArchiveZip = restic.ArchiveZip
+)
+
+type SnapshotClient struct {
+	storage *storage.StorageClient
+}
+
+type SnapshotClientOption struct {
+	Name                 string
+	UserName             string
+	Password             string
+	PasswordProvider     PasswordProvider
+	CloudName            string
+	CloudRegion          string
+	CloudApiMirror       string
+	CloudEndpoint        string
+	StorageTokenDuration string
+	Progress             ProgressFunc
+	Backend              Backend
+	BaseDir              string
+	Version              string
+	Logger               *zap.SugaredLogger
+}
+
+func NewSnapshotClient(opt *SnapshotClientOption) *SnapshotClient {
+	var client = &SnapshotClient{
+		storage: &storage.StorageClient{
+			Name:                 opt.Name,
+			UserName:             opt.UserName,
+			Password:             opt.Password,
+			PasswordProvider:     opt.PasswordProvider,
+			CloudName:            opt.CloudName,
+			CloudRegion:          opt.CloudRegion,
+			CloudApiMirror:       opt.CloudApiMirror,
+			CloudEndpoint:        opt.CloudEndpoint,
+			StorageTokenDuration: opt.StorageTokenDuration,
+			Progress:             opt.Progress,
+			Backend:              opt.Backend,
+		},
+	}
+
+	setLogger(opt.BaseDir, opt.Version, "backup_snapshot.log", opt.Logger)
+
+	return client
+}
+
+// List returns the snapshots of the named repository in the order restic
+// reports them, oldest first.
+func (c *SnapshotClient) List(ctx context.Context, filter *SnapshotFilter) ([]*Snapshot, error) {
+	return c.storage.ListSnapshots(ctx, filter)
+}
+
+// Tag adds tags to the snapshots snapshotIds of the named repository, e.g.
+// "keep" to protect them from a retention policy with KeepTags. restic saves
+// a tagged snapshot under a new id.
+func (c *SnapshotClient) Tag(ctx context.Context, snapshotIds []string, tags ...string) error {
+	return c.storage.Tag(ctx, snapshotIds, tags...)
+}
+
+// Untag removes tags from the snapshots snapshotIds of the named repository.
+func (c *SnapshotClient) Untag(ctx context.Context, snapshotIds []string, tags ...string) error {
+	return c.storage.Untag(ctx, snapshotIds, tags...)
+}
+
+// ListFiles returns the entries of the directory dir in the snapshot, dir is
+// an absolute path as backed up, e.g. "/olares/data", and the root of the
+// snapshot when empty.
+func (c *SnapshotClient) ListFiles(ctx context.Context, snapshotId string, dir string) ([]*Node, error) {
+	return c.storage.ListFiles(ctx, snapshotId, dir)
+}
+
+// Find searches the snapshots of the named repository for the files matching
+// pattern, matches are grouped by snapshot.
+func (c *SnapshotClient) Find(ctx context.Context, pattern string, filter *FindFilter) ([]*FindMatch, error) {
+	return c.storage.Find(ctx, pattern, filter)
+}
+
+// Diff reports the files added, removed and modified from snapshot snapA
+// to snapshot snapB, e.g. to show what changed since the last backup.
+func (c *SnapshotClient) Diff(ctx context.Context, snapA string, snapB string) (*DiffReport, error) {
+	return c.storage.Diff(ctx, snapA, snapB)
+}
+
+// Dump streams the file p of the snapshot without restoring it to disk, p is
+// an absolute path as backed up. The caller must close the reader, closing
+// it early stops restic.
+func (c *SnapshotClient) Dump(ctx context.Context, snapshotId string, p string) (io.ReadCloser, error) {
+	return c.storage.Dump(ctx, snapshotId, p, "")
+}
+
+// DumpArchive streams the directory p of the snapshot packed as archive.
+func (c *SnapshotClient) DumpArchive(ctx context.Context, snapshotId string, p string, archive ArchiveFormat) (io.ReadCloser, error) {
+	return c.storage.Dump(ctx, snapshotId, p, archive)
+}
+
+// CopyTo replicates the snapshots of the named repository that match filter
+// to the repository of the same name in dst, e.g. a LocalBackend on a NAS, a
+// RestBackend or an Olares Space in another region. The copy uses the same
+// password, and snapshots already copied are skipped.
+//
+// restic reads the credentials of both repositories from the same variables,
+// so a copy between backends that need different credentials, such as two
+// Olares Spaces or two S3 accounts, goes through a temporary local repository
+// and needs the disk space of the copied snapshots.
+func (c *SnapshotClient) CopyTo(ctx context.Context, dst Backend, filter *SnapshotFilter) (*CopyReport, error) {
+	return c.storage.CopyTo(ctx, dst, filter)
+}
+
+// Forget removes the snapshots of the named repository that policy does not
+// keep, their data is only deleted when policy.Prune is set or Prune is run.
+func (c *SnapshotClient) Forget(ctx context.Context, policy *RetentionPolicy) (*ForgetReport, error) {
+	return c.storage.Forget(ctx, policy)
+}
+
+func (c *SnapshotClient) Prune(ctx context.Context) error {
+	return c.storage.Prune(ctx)
+}